@@ -1,17 +1,27 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"app/configs"
 	"app/internal/entity"
 	"app/internal/infra/database"
+	"app/internal/infra/mail"
 	"app/internal/infra/webserver/handlers"
+	"app/internal/infra/webserver/middlewares"
+	"app/pkg/jwtkeys"
+	"app/pkg/passhash"
+	"app/pkg/problem"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
@@ -40,6 +50,11 @@ import (
 // @name        	Authorization
 // @description  	Authorization header with JWT Bearer token
 
+// @securityDefinitions.apiKey XApiKeyAuth
+// @in          	header
+// @name        	X-API-Key
+// @description  	Scoped API key, accepted on product routes
+
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
 func main() {
@@ -48,40 +63,270 @@ func main() {
 		panic(err)
 	}
 
+	entity.SetPasswordPolicy(entity.PasswordPolicy{
+		MinLength:     config.PasswordMinLength,
+		RequireUpper:  config.PasswordRequireUpper,
+		RequireLower:  config.PasswordRequireLower,
+		RequireDigit:  config.PasswordRequireDigit,
+		RequireSymbol: config.PasswordRequireSymbol,
+	})
+
+	passwordHasher, err := passhash.NewHasher(
+		config.PasswordHashAlgorithm,
+		config.PasswordBcryptCost,
+		passhash.Argon2idParams{
+			Memory:      config.PasswordArgon2Memory,
+			Iterations:  config.PasswordArgon2Iterations,
+			Parallelism: config.PasswordArgon2Parallelism,
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+	entity.SetPasswordHasher(passwordHasher)
+
 	db, err := gorm.Open(sqlite.Open("sqlite.test.db"), &gorm.Config{})
 	if err != nil {
 		panic(err)
 	}
 
-	db.AutoMigrate(&entity.User{}, &entity.Product{})
+	if err := database.NormalizeEmails(db); err != nil {
+		panic(err)
+	}
+
+	if err := database.MigrateProductPrices(db, config.LegacyPriceCurrency); err != nil {
+		panic(err)
+	}
+
+	db.AutoMigrate(
+		&entity.User{},
+		&entity.Product{},
+		&entity.ProductRevision{},
+		&entity.RefreshToken{},
+		&entity.RevokedToken{},
+		&entity.UserTokenRevocation{},
+		&entity.PasswordResetToken{},
+		&entity.EmailVerificationToken{},
+		&entity.AccountLockout{},
+		&entity.LoginAttempt{},
+		&entity.RecoveryCode{},
+		&entity.APIKey{},
+		&entity.OAuthClient{},
+		&entity.AuditEvent{},
+	)
+
+	auditLog := database.NewAuditLog(db)
+	auditor := handlers.NewAuditor(auditLog)
 
 	productDB := database.NewProduct(db)
-	productHandler := handlers.NeProductHandler(productDB)
+	productHandler := handlers.NeProductHandler(productDB, auditor)
 
 	userDB := database.NewUser(db)
-	userHandler := handlers.NewUserHandler(userDB, config.TokenAuth, config.JwtExpiresIn)
+	if err := bootstrapAdmin(userDB, config.AdminName, config.AdminEmail, config.AdminPassword); err != nil {
+		panic(err)
+	}
+
+	mailer, err := newMailer(
+		config.MailDriver,
+		config.MailFrom,
+		config.MailLogFile,
+		config.SmtpHost,
+		config.SmtpPort,
+		config.SmtpUsername,
+		config.SmtpPassword,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	var loginThrottleDB database.LoginThrottleInterface = database.NewLoginThrottle(db)
+	if config.LoginThrottleStore == "memory" {
+		loginThrottleDB = database.NewInMemoryLoginThrottle()
+	}
+
+	loginThrottler := handlers.NewLoginThrottler(loginThrottleDB, entity.LoginThrottlePolicy{
+		MaxFailures:     config.LoginMaxFailures,
+		BaseDelay:       time.Second * time.Duration(config.LoginBackoffBase),
+		MaxDelay:        time.Second * time.Duration(config.LoginBackoffMax),
+		LockoutDuration: time.Second * time.Duration(config.LoginLockoutDuration),
+		IPMaxAttempts:   config.LoginIPMaxAttempts,
+		IPWindow:        time.Second * time.Duration(config.LoginIPWindow),
+	})
+
+	emailVerificationHandler := handlers.NewEmailVerificationHandler(
+		userDB,
+		database.NewEmailVerificationToken(db),
+		mailer,
+		loginThrottler,
+		config.EmailVerificationExpiresIn,
+		config.AppURL,
+	)
+
+	refreshTokenDB := database.NewRefreshToken(db)
+	tokenRevocationDB := database.NewTokenRevocation(db)
+	tokenIssuer := handlers.NewTokenIssuer(
+		refreshTokenDB,
+		config.TokenAuth,
+		config.JwtExpiresIn,
+		config.JwtRefreshExpiresIn,
+		config.MfaChallengeExpiresIn,
+		config.OAuthTokenExpiresIn,
+	)
+	userHandler := handlers.NewUserHandler(
+		userDB,
+		refreshTokenDB,
+		tokenRevocationDB,
+		tokenIssuer,
+		emailVerificationHandler,
+		config.RequireVerifiedEmail,
+		loginThrottler,
+		auditor,
+	)
+
+	passwordResetHandler := handlers.NewPasswordResetHandler(
+		userDB,
+		database.NewPasswordResetToken(db),
+		refreshTokenDB,
+		tokenRevocationDB,
+		mailer,
+		loginThrottler,
+		config.PasswordResetExpiresIn,
+	)
+
+	twoFactorHandler := handlers.NewTwoFactorHandler(
+		userDB,
+		database.NewRecoveryCode(db),
+		tokenRevocationDB,
+		tokenIssuer,
+		loginThrottler,
+		auditor,
+		config.TotpIssuer,
+	)
+
+	apiKeyDB := database.NewAPIKey(db)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyDB)
+
+	oauthClientDB := database.NewOAuthClient(db)
+	oauthHandler := handlers.NewOAuthHandler(oauthClientDB, tokenRevocationDB, tokenIssuer)
+	oauthClientHandler := handlers.NewOAuthClientHandler(oauthClientDB, tokenRevocationDB)
+
+	auditHandler := handlers.NewAuditHandler(auditLog)
+	adminUserHandler := handlers.NewAdminUserHandler(
+		userDB,
+		refreshTokenDB,
+		tokenRevocationDB,
+		passwordResetHandler,
+		auditor,
+	)
+
+	go rotateSigningKeyOnHangup(config.TokenAuth, config.SigningKey)
+	go purgeProductTrash(
+		productDB,
+		time.Second*time.Duration(config.ProductTrashRetention),
+		time.Second*time.Duration(config.ProductTrashPurgeInterval),
+	)
+	go deleteExpiredRevokedTokens(
+		tokenRevocationDB,
+		time.Second*time.Duration(config.RevokedTokenPurgeInterval),
+	)
 
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(middlewares.LimitBodySize(config.MaxRequestBodySize))
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, http.StatusNotFound, "")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, http.StatusMethodNotAllowed, "")
+	})
+
+	r.Get("/.well-known/jwks.json", handlers.NewJwksHandler(config.TokenAuth).GetJwks)
+
+	r.Post("/oauth/token", oauthHandler.Token)
+	r.Post("/oauth/introspect", oauthHandler.Introspect)
 
 	prefix := "/api/v1"
 
 	r.Route(prefix, func(r chi.Router) {
 		r.Route("/products", func(r chi.Router) {
-			r.Use(jwtauth.Verifier(config.TokenAuth))
-			r.Use(jwtauth.Authenticator)
-
-			r.Post("/", productHandler.CreateProduct)
-			r.Get("/", productHandler.FindManyProducts)
-			r.Get("/{id}", productHandler.GetProduct)
-			r.Put("/{id}", productHandler.UpdateProduct)
-			r.Delete("/{id}", productHandler.DeleteProduct)
+			r.Use(middlewares.Authenticate(config.TokenAuth, tokenRevocationDB, apiKeyDB, userDB))
+
+			viewer := r.With(middlewares.RequirePermission(entity.RoleViewer, entity.ScopeProductsRead))
+			editor := r.With(middlewares.RequirePermission(entity.RoleEditor, entity.ScopeProductsWrite))
+
+			editor.Post("/", productHandler.CreateProduct)
+			viewer.Get("/", productHandler.FindManyProducts)
+			editor.Get("/trash", productHandler.TrashProducts)
+			editor.With(middlewares.RequireRole(entity.RoleAdmin)).Delete("/trash/{id}", productHandler.PurgeProduct)
+			viewer.Get("/{id}", productHandler.GetProduct)
+			editor.Put("/{id}", productHandler.UpdateProduct)
+			editor.Patch("/{id}", productHandler.PatchProduct)
+			editor.Delete("/{id}", productHandler.DeleteProduct)
+			editor.Post("/{id}/restore", productHandler.RestoreProduct)
+			viewer.Get("/{id}/revisions", productHandler.ListProductRevisions)
+			viewer.Get("/{id}/revisions/diff", productHandler.DiffProductRevisions)
+			viewer.Get("/{id}/revisions/{n}", productHandler.GetProductRevision)
+			editor.Post("/{id}/revert/{n}", productHandler.RevertProduct)
 		})
 
 		r.Route("/users", func(r chi.Router) {
 			r.Post("/", userHandler.CreateUser)
 			r.Post("/generate-jwt", userHandler.GetJwt)
+			r.Post("/generate-jwt/mfa", twoFactorHandler.VerifyMfa)
+			r.Post("/refresh-token", userHandler.RefreshToken)
+			r.Post("/password-reset/request", passwordResetHandler.RequestPasswordReset)
+			r.Post("/password-reset/confirm", passwordResetHandler.ConfirmPasswordReset)
+			r.Get("/verify", emailVerificationHandler.VerifyEmail)
+			r.Post("/verify/resend", emailVerificationHandler.ResendVerification)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middlewares.Verifier(config.TokenAuth))
+				r.Use(middlewares.Authenticator)
+				r.Use(middlewares.RequireAccessToken)
+				r.Use(middlewares.RejectRevokedTokens(tokenRevocationDB))
+
+				r.Post("/logout", userHandler.Logout)
+				r.Post("/logout-all", userHandler.LogoutAll)
+
+				r.Get("/me", userHandler.GetMe)
+				r.Patch("/me", userHandler.UpdateMe)
+				r.Delete("/me", userHandler.DeleteMe)
+				r.Put("/me/password", userHandler.ChangePassword)
+
+				r.Post("/me/2fa/enroll", twoFactorHandler.EnrollTotp)
+				r.Post("/me/2fa/confirm", twoFactorHandler.ConfirmTotp)
+				r.Post("/me/2fa/disable", twoFactorHandler.DisableTotp)
+
+				r.Post("/me/api-keys", apiKeyHandler.CreateAPIKey)
+				r.Get("/me/api-keys", apiKeyHandler.ListAPIKeys)
+				r.Delete("/me/api-keys/{id}", apiKeyHandler.RevokeAPIKey)
+			})
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middlewares.Verifier(config.TokenAuth))
+			r.Use(middlewares.Authenticator)
+			r.Use(middlewares.RequireAccessToken)
+			r.Use(middlewares.RejectRevokedTokens(tokenRevocationDB))
+			r.Use(middlewares.RequireRole(entity.RoleAdmin))
+
+			r.Post("/oauth-clients", oauthClientHandler.CreateClient)
+			r.Get("/oauth-clients", oauthClientHandler.ListClients)
+			r.Get("/oauth-clients/{id}", oauthClientHandler.GetClient)
+			r.Post("/oauth-clients/{id}/secret", oauthClientHandler.RotateClientSecret)
+			r.Delete("/oauth-clients/{id}", oauthClientHandler.DeleteClient)
+
+			r.Get("/users", adminUserHandler.ListUsers)
+			r.Get("/users/{id}", adminUserHandler.GetUser)
+			r.Post("/users/{id}/disable", adminUserHandler.DisableUser)
+			r.Post("/users/{id}/enable", adminUserHandler.EnableUser)
+			r.Post("/users/{id}/password-reset", adminUserHandler.ForcePasswordReset)
+			r.Put("/users/{id}/role", adminUserHandler.ChangeUserRole)
+			r.Delete("/users/{id}", adminUserHandler.DeleteUser)
+
+			r.Get("/audit-events", auditHandler.ListAuditEvents)
 		})
 	})
 
@@ -89,3 +334,109 @@ func main() {
 
 	http.ListenAndServe(fmt.Sprintf(":%s", config.WebServerPort), r)
 }
+
+// bootstrapAdmin creates the admin account configured through ADMIN_EMAIL when
+// it does not exist yet, so a fresh database always has someone able to manage
+// roles.
+func bootstrapAdmin(userDB database.UserInterface, name, email, password string) error {
+	if email == "" || password == "" {
+		return nil
+	}
+
+	_, err := userDB.FindByEmail(email)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return err
+	}
+
+	admin, err := entity.NewUser(name, email, password)
+	if err != nil {
+		return err
+	}
+
+	admin.Role = entity.RoleAdmin
+	admin.VerifyEmail()
+
+	return userDB.Create(admin)
+}
+
+// rotateSigningKeyOnHangup reloads the signing key on SIGHUP. When the key
+// changed the previous one keeps verifying tokens until they have expired.
+func rotateSigningKeyOnHangup(keys *jwtkeys.KeyRing, loadKey func() (*jwtkeys.Key, error)) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	for range hangup {
+		key, err := loadKey()
+		if err == nil {
+			err = keys.Rotate(key)
+		}
+		if err != nil {
+			log.Printf("signing key rotation: %v", err)
+			continue
+		}
+
+		log.Printf("signing key %s in use", key.ID)
+	}
+}
+
+// purgeProductTrash permanently deletes, every interval, the products that
+// have been in the trash for longer than retention. A retention or interval
+// of zero keeps deleted products until they are purged by hand.
+func purgeProductTrash(productDB database.ProductInterface, retention, interval time.Duration) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		purged, err := productDB.PurgeDeletedBefore(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("product trash purge: %v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("purged %d products from the trash", purged)
+		}
+	}
+}
+
+// deleteExpiredRevokedTokens forgets, every interval, the revoked tokens that
+// have expired anyway. An interval of zero keeps them.
+func deleteExpiredRevokedTokens(tokenRevocationDB database.TokenRevocationInterface, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		if err := tokenRevocationDB.DeleteExpired(); err != nil {
+			log.Printf("revoked token purge: %v", err)
+		}
+	}
+}
+
+// newMailer returns the SMTP mailer when MAIL_DRIVER is "smtp" and otherwise a
+// mailer that writes messages to MAIL_LOG_FILE, or stdout when it is empty.
+func newMailer(driver, from, logFile, smtpHost, smtpPort, smtpUsername, smtpPassword string) (mail.Mailer, error) {
+	if driver == "smtp" {
+		return mail.NewSMTPMailer(smtpHost, smtpPort, smtpUsername, smtpPassword, from), nil
+	}
+
+	if logFile == "" {
+		return mail.NewLogMailer(from, os.Stdout), nil
+	}
+
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return mail.NewLogMailer(from, f), nil
+}
@@ -1,21 +1,68 @@
 package configs
 
 import (
-	"github.com/go-chi/jwtauth"
+	"strings"
+	"time"
+
+	"app/pkg/jwtkeys"
+
 	"github.com/spf13/viper"
 )
 
 type conf struct {
-	DbDriver      string `mapstructure:"DB_DRIVER"`
-	DbHost        string `mapstructure:"DB_HOST"`
-	DbPort        string `mapstructure:"DB_PORT"`
-	DbUser        string `mapstructure:"DB_USER"`
-	DbPassword    string `mapstructure:"DB_PASSWORD"`
-	DbName        string `mapstructure:"DB_NAME"`
-	WebServerPort string `mapstructure:"WEB_SERVER_PORT"`
-	JwtSecret     string `mapstructure:"JWT_SECRET"`
-	JwtExpiresIn  int    `mapstructure:"JWT_EXPIRES_IN"`
-	TokenAuth     *jwtauth.JWTAuth
+	DbDriver                   string `mapstructure:"DB_DRIVER"`
+	DbHost                     string `mapstructure:"DB_HOST"`
+	DbPort                     string `mapstructure:"DB_PORT"`
+	DbUser                     string `mapstructure:"DB_USER"`
+	DbPassword                 string `mapstructure:"DB_PASSWORD"`
+	DbName                     string `mapstructure:"DB_NAME"`
+	WebServerPort              string `mapstructure:"WEB_SERVER_PORT"`
+	MaxRequestBodySize         int64  `mapstructure:"MAX_REQUEST_BODY_SIZE"`
+	JwtAlgorithm               string `mapstructure:"JWT_ALGORITHM"`
+	JwtSecret                  string `mapstructure:"JWT_SECRET"`
+	JwtPrivateKeyFile          string `mapstructure:"JWT_PRIVATE_KEY_FILE"`
+	JwtPreviousKeyFiles        string `mapstructure:"JWT_PREVIOUS_KEY_FILES"`
+	JwtExpiresIn               int    `mapstructure:"JWT_EXPIRES_IN"`
+	JwtRefreshExpiresIn        int    `mapstructure:"JWT_REFRESH_EXPIRES_IN"`
+	AdminName                  string `mapstructure:"ADMIN_NAME"`
+	AdminEmail                 string `mapstructure:"ADMIN_EMAIL"`
+	AdminPassword              string `mapstructure:"ADMIN_PASSWORD"`
+	AppURL                     string `mapstructure:"APP_URL"`
+	PasswordResetExpiresIn     int    `mapstructure:"PASSWORD_RESET_EXPIRES_IN"`
+	EmailVerificationExpiresIn int    `mapstructure:"EMAIL_VERIFICATION_EXPIRES_IN"`
+	RequireVerifiedEmail       bool   `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	MailDriver                 string `mapstructure:"MAIL_DRIVER"`
+	MailFrom                   string `mapstructure:"MAIL_FROM"`
+	MailLogFile                string `mapstructure:"MAIL_LOG_FILE"`
+	SmtpHost                   string `mapstructure:"SMTP_HOST"`
+	SmtpPort                   string `mapstructure:"SMTP_PORT"`
+	SmtpUsername               string `mapstructure:"SMTP_USERNAME"`
+	SmtpPassword               string `mapstructure:"SMTP_PASSWORD"`
+	PasswordMinLength          int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireUpper       bool   `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower       bool   `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit       bool   `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol      bool   `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordHashAlgorithm      string `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	PasswordBcryptCost         int    `mapstructure:"PASSWORD_BCRYPT_COST"`
+	PasswordArgon2Memory       uint32 `mapstructure:"PASSWORD_ARGON2_MEMORY"`
+	PasswordArgon2Iterations   uint32 `mapstructure:"PASSWORD_ARGON2_ITERATIONS"`
+	PasswordArgon2Parallelism  uint8  `mapstructure:"PASSWORD_ARGON2_PARALLELISM"`
+	LoginThrottleStore         string `mapstructure:"LOGIN_THROTTLE_STORE"`
+	LoginMaxFailures           int    `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginBackoffBase           int    `mapstructure:"LOGIN_BACKOFF_BASE"`
+	LoginBackoffMax            int    `mapstructure:"LOGIN_BACKOFF_MAX"`
+	LoginLockoutDuration       int    `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginIPMaxAttempts         int    `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginIPWindow              int    `mapstructure:"LOGIN_IP_WINDOW"`
+	TotpIssuer                 string `mapstructure:"TOTP_ISSUER"`
+	MfaChallengeExpiresIn      int    `mapstructure:"MFA_CHALLENGE_EXPIRES_IN"`
+	OAuthTokenExpiresIn        int    `mapstructure:"OAUTH_TOKEN_EXPIRES_IN"`
+	ProductTrashRetention      int    `mapstructure:"PRODUCT_TRASH_RETENTION"`
+	ProductTrashPurgeInterval  int    `mapstructure:"PRODUCT_TRASH_PURGE_INTERVAL"`
+	LegacyPriceCurrency        string `mapstructure:"LEGACY_PRICE_CURRENCY"`
+	RevokedTokenPurgeInterval  int    `mapstructure:"REVOKED_TOKEN_PURGE_INTERVAL"`
+	TokenAuth                  *jwtkeys.KeyRing
 }
 
 var cfg *conf
@@ -37,7 +84,66 @@ func LoadConfig(envPath string) (*conf, error) {
 		panic(err)
 	}
 
-	cfg.TokenAuth = jwtauth.New("HS256", []byte(cfg.JwtSecret), nil)
+	cfg.TokenAuth, err = newTokenAuth(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
+
+// newTokenAuth signs with JWT_SECRET for the HMAC algorithms and with the key
+// of JWT_PRIVATE_KEY_FILE otherwise. Keys of JWT_PREVIOUS_KEY_FILES only
+// verify tokens issued before the last rotation, until those have expired.
+func newTokenAuth(cfg *conf) (*jwtkeys.KeyRing, error) {
+	signing, err := cfg.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	ring, err := jwtkeys.NewKeyRing(signing, cfg.TokenLifetime())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range strings.Split(cfg.JwtPreviousKeyFiles, ",") {
+		if strings.TrimSpace(path) == "" {
+			continue
+		}
+
+		key, err := jwtkeys.LoadVerificationKey(strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		ring.Retire(key)
+	}
+
+	return ring, nil
+}
+
+// SigningKey reads the configured signing key. It is called again on SIGHUP
+// so the key file can be rotated without a restart.
+func (c *conf) SigningKey() (*jwtkeys.Key, error) {
+	alg := c.JwtAlgorithm
+	if alg == "" {
+		alg = "HS256"
+	}
+
+	if strings.HasPrefix(alg, "HS") {
+		return jwtkeys.NewSecretKey(alg, []byte(c.JwtSecret))
+	}
+
+	return jwtkeys.LoadPrivateKey(alg, c.JwtPrivateKeyFile)
+}
+
+// TokenLifetime is the longest time a token signed by us stays valid.
+func (c *conf) TokenLifetime() time.Duration {
+	lifetime := c.JwtExpiresIn
+	for _, expiresIn := range []int{c.MfaChallengeExpiresIn, c.OAuthTokenExpiresIn} {
+		if expiresIn > lifetime {
+			lifetime = expiresIn
+		}
+	}
+
+	return time.Second * time.Duration(lifetime)
+}
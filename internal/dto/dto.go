@@ -1,13 +1,37 @@
 package dto
 
+import (
+	"time"
+
+	"app/internal/entity"
+	"app/pkg/money"
+)
+
+// Prices are a decimal amount, as a string, and an ISO 4217 currency code.
 type CreateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name  string      `json:"name"`
+	Price money.Money `json:"price" swaggertype:"object,string" example:"amount:197.98,currency:USD"`
 }
 
 type UpdateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name  string      `json:"name"`
+	Price money.Money `json:"price" swaggertype:"object,string" example:"amount:197.98,currency:USD"`
+}
+
+// ProductOutput is a product with the entity tag of its version, to send back
+// in If-Match when changing it.
+type ProductOutput struct {
+	entity.Product
+	ETag string `json:"etag"`
+}
+
+// ProductRevisionDiff lists the fields of a product changed between two of
+// its revisions.
+type ProductRevisionDiff struct {
+	ProductID string               `json:"product_id"`
+	From      int                  `json:"from"`
+	To        int                  `json:"to"`
+	Changes   []entity.FieldChange `json:"changes"`
 }
 
 type CreateUserInput struct {
@@ -22,5 +46,173 @@ type GetJwtInput struct {
 }
 
 type GetJwtOutput struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type MfaChallengeOutput struct {
+	MfaRequired bool   `json:"mfa_required"`
+	MfaToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type MfaVerifyInput struct {
+	MfaToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type UserOutput struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UpdateMeInput struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type DeleteMeInput struct {
+	Password string `json:"password"`
+}
+
+type PasswordResetRequestInput struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmInput struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type MessageOutput struct {
+	Message string `json:"message"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email"`
+}
+
+type TotpEnrollOutput struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TotpConfirmInput struct {
+	Code string `json:"code"`
+}
+
+type TotpDisableInput struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type RecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type CreateAPIKeyInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyOutput struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyOutput struct {
+	APIKeyOutput
+	Key string `json:"key"`
+}
+
+type OAuthTokenOutput struct {
 	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+type OAuthErrorOutput struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type IntrospectionOutput struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+type CreateOAuthClientInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type OAuthClientOutput struct {
+	ClientID  string    `json:"client_id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OAuthClientSecretOutput struct {
+	OAuthClientOutput
+	ClientSecret string `json:"client_secret"`
+}
+
+type AuditEventPageOutput struct {
+	Items []entity.AuditEvent `json:"items"`
+	Page  int                 `json:"page"`
+	Limit int                 `json:"limit"`
+	Total int64               `json:"total"`
+}
+
+type AdminUserOutput struct {
+	ID                    string     `json:"id"`
+	Name                  string     `json:"name"`
+	Email                 string     `json:"email"`
+	Role                  string     `json:"role"`
+	EmailVerifiedAt       *time.Time `json:"email_verified_at"`
+	TotpEnabled           bool       `json:"totp_enabled"`
+	DisabledAt            *time.Time `json:"disabled_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+}
+
+type AdminUserPageOutput struct {
+	Items []AdminUserOutput `json:"items"`
+	Page  int               `json:"page"`
+	Limit int               `json:"limit"`
+	Total int64             `json:"total"`
+}
+
+type UpdateUserRoleInput struct {
+	Role string `json:"role"`
 }
@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"app/pkg/entity"
+)
+
+var (
+	ErrAPIKeyNameIsRequired = errors.New("api key name is required")
+	ErrExpiryInPast         = errors.New("expiry must be in the future")
+)
+
+// APIKeyPrefix starts every API key, making leaked keys easy to spot.
+const APIKeyPrefix = "ak_"
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id" gorm:"index"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex"`
+	Scopes     string     `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewAPIKey issues a key acting for the user within the given scopes and
+// returns it with the plain key, which is never persisted. A nil expiresAt
+// never expires.
+func NewAPIKey(userID, name string, scopes []Scope, expiresAt *time.Time) (*APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrAPIKeyNameIsRequired
+	}
+
+	joined, err := JoinScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, "", ErrExpiryInPast
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	key := APIKeyPrefix + token
+
+	return &APIKey{
+		ID:        entity.NewID().String(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:len(APIKeyPrefix)+8],
+		KeyHash:   HashToken(key),
+		Scopes:    joined,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}, key, nil
+}
+
+func (k *APIKey) ScopeList() []Scope {
+	return SplitScopes(k.Scopes)
+}
+
+func (k *APIKey) HasScope(scope Scope) bool {
+	return HasScope(k.Scopes, scope)
+}
+
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
@@ -0,0 +1,56 @@
+package entity_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"app/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	scopes := []entity.Scope{entity.ScopeProductsRead, entity.ScopeProductsWrite}
+	k, key, err := entity.NewAPIKey("user-id", " batch job ", scopes, nil)
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, k.ID)
+	assert.Equal(t, "user-id", k.UserID)
+	assert.Equal(t, "batch job", k.Name)
+	assert.True(t, strings.HasPrefix(key, entity.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(key, k.Prefix))
+	assert.Equal(t, entity.HashToken(key), k.KeyHash)
+	assert.Equal(t, scopes, k.ScopeList())
+	assert.True(t, k.HasScope(entity.ScopeProductsWrite))
+	assert.False(t, k.IsExpired())
+	assert.False(t, k.IsRevoked())
+}
+
+func TestNewAPIKeyValidation(t *testing.T) {
+	read := []entity.Scope{entity.ScopeProductsRead}
+	past := time.Now().Add(-time.Minute)
+
+	_, _, err := entity.NewAPIKey("user-id", "", read, nil)
+	assert.Equal(t, entity.ErrAPIKeyNameIsRequired, err)
+
+	_, _, err = entity.NewAPIKey("user-id", "job", nil, nil)
+	assert.Equal(t, entity.ErrScopeIsRequired, err)
+
+	_, _, err = entity.NewAPIKey("user-id", "job", []entity.Scope{"users:write"}, nil)
+	assert.Equal(t, entity.ErrInvalidScope, err)
+
+	_, _, err = entity.NewAPIKey("user-id", "job", read, &past)
+	assert.Equal(t, entity.ErrExpiryInPast, err)
+}
+
+func TestAPIKeyIsExpired(t *testing.T) {
+	expiresAt := time.Now().Add(time.Minute)
+	k, _, _ := entity.NewAPIKey("user-id", "job", []entity.Scope{entity.ScopeProductsRead}, &expiresAt)
+	assert.False(t, k.IsExpired())
+	assert.False(t, k.HasScope(entity.ScopeProductsWrite))
+
+	past := time.Now().Add(-time.Minute)
+	k.ExpiresAt = &past
+	assert.True(t, k.IsExpired())
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"app/pkg/entity"
+)
+
+type AuditAction string
+
+const (
+	AuditLoginSucceeded          AuditAction = "login.succeeded"
+	AuditLoginFailed             AuditAction = "login.failed"
+	AuditUserCreated             AuditAction = "user.created"
+	AuditUserDisabled            AuditAction = "user.disabled"
+	AuditUserEnabled             AuditAction = "user.enabled"
+	AuditUserRoleChanged         AuditAction = "user.role_changed"
+	AuditUserPasswordResetForced AuditAction = "user.password_reset_forced"
+	AuditUserDeleted             AuditAction = "user.deleted"
+	AuditProductCreated          AuditAction = "product.created"
+	AuditProductUpdated          AuditAction = "product.updated"
+	AuditProductDeleted          AuditAction = "product.deleted"
+	AuditProductRestored         AuditAction = "product.restored"
+	AuditProductPurged           AuditAction = "product.purged"
+)
+
+// Actor types of an audit event, derived from the credential of the request.
+const (
+	ActorAnonymous = "anonymous"
+	ActorUser      = "user"
+	ActorAPIKey    = "api_key"
+	ActorClient    = "client"
+)
+
+// AuditEvent is an entry of the append-only security audit log. Before and
+// After hold JSON snapshots of the resource around a change.
+type AuditEvent struct {
+	ID           string          `json:"id"`
+	Action       AuditAction     `json:"action" gorm:"index"`
+	ActorID      string          `json:"actor_id" gorm:"index"`
+	ActorType    string          `json:"actor_type"`
+	ResourceType string          `json:"resource_type" gorm:"index:idx_audit_events_resource"`
+	ResourceID   string          `json:"resource_id" gorm:"index:idx_audit_events_resource"`
+	IP           string          `json:"ip"`
+	UserAgent    string          `json:"user_agent"`
+	RequestID    string          `json:"request_id"`
+	Details      string          `json:"details"`
+	Before       json.RawMessage `json:"before" swaggertype:"object"`
+	After        json.RawMessage `json:"after" swaggertype:"object"`
+	CreatedAt    time.Time       `json:"created_at" gorm:"index"`
+}
+
+func NewAuditEvent(action AuditAction, resourceType, resourceID string) *AuditEvent {
+	return &AuditEvent{
+		ID:           entity.NewID().String(),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// SetSnapshots stores the state of the resource before and after the change.
+// Either may be nil, for creations and deletions.
+func (e *AuditEvent) SetSnapshots(before, after interface{}) error {
+	var err error
+
+	if before != nil {
+		e.Before, err = json.Marshal(before)
+		if err != nil {
+			return err
+		}
+	}
+
+	if after != nil {
+		e.After, err = json.Marshal(after)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
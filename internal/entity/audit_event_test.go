@@ -0,0 +1,37 @@
+package entity_test
+
+import (
+	"testing"
+
+	"app/internal/entity"
+	"app/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuditEvent(t *testing.T) {
+	e := entity.NewAuditEvent(entity.AuditProductUpdated, "product", "product-id")
+
+	assert.NotEmpty(t, e.ID)
+	assert.Equal(t, entity.AuditProductUpdated, e.Action)
+	assert.Equal(t, "product", e.ResourceType)
+	assert.Equal(t, "product-id", e.ResourceID)
+	assert.False(t, e.CreatedAt.IsZero())
+}
+
+func TestAuditEventSetSnapshots(t *testing.T) {
+	e := entity.NewAuditEvent(entity.AuditProductUpdated, "product", "product-id")
+
+	before, _ := entity.NewProduct("Before", money.MustParse("10", "USD"))
+	after := *before
+	after.Name = "After"
+
+	assert.Nil(t, e.SetSnapshots(before, &after))
+	assert.Contains(t, string(e.Before), `"name":"Before"`)
+	assert.Contains(t, string(e.After), `"name":"After"`)
+
+	created := entity.NewAuditEvent(entity.AuditProductCreated, "product", "product-id")
+	assert.Nil(t, created.SetSnapshots(nil, &after))
+	assert.Nil(t, created.Before)
+	assert.NotNil(t, created.After)
+}
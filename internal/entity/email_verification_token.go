@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+
+	"app/pkg/entity"
+)
+
+type EmailVerificationToken struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewEmailVerificationToken returns a single use token confirming the email of
+// the user and the plain token string to be mailed, only its hash is persisted.
+func NewEmailVerificationToken(userID string, expiresIn time.Duration) (*EmailVerificationToken, string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+
+	return &EmailVerificationToken{
+		ID:        entity.NewID().String(),
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		ExpiresAt: now.Add(expiresIn),
+		CreatedAt: now,
+	}, token, nil
+}
+
+func (t *EmailVerificationToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *EmailVerificationToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
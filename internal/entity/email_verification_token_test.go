@@ -0,0 +1,28 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEmailVerificationToken(t *testing.T) {
+	evt, token, err := entity.NewEmailVerificationToken("user-id", time.Hour)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, evt)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, "user-id", evt.UserID)
+	assert.Equal(t, entity.HashToken(token), evt.TokenHash)
+	assert.False(t, evt.IsExpired())
+	assert.False(t, evt.IsUsed())
+}
+
+func TestEmailVerificationTokenIsExpired(t *testing.T) {
+	evt, _, _ := entity.NewEmailVerificationToken("user-id", -time.Second)
+
+	assert.True(t, evt.IsExpired())
+}
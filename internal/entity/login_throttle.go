@@ -0,0 +1,105 @@
+package entity
+
+import "time"
+
+// LoginThrottlePolicy configures how credential checks are slowed down. A zero
+// value in any limit disables that limit.
+type LoginThrottlePolicy struct {
+	MaxFailures     int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	IPMaxAttempts   int
+	IPWindow        time.Duration
+}
+
+// Delay returns how long an account has to wait after its nth consecutive
+// failure. It doubles from BaseDelay on every failure, up to MaxDelay, and
+// becomes LockoutDuration once MaxFailures is reached.
+func (p LoginThrottlePolicy) Delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+
+	if p.MaxFailures > 0 && failures >= p.MaxFailures {
+		return p.LockoutDuration
+	}
+
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+
+	return delay
+}
+
+// AccountLockout tracks consecutive failed logins for an email, whether or not
+// an account with that email exists. Version is 0 until it is stored, and
+// orders concurrent changes.
+type AccountLockout struct {
+	Email         string    `json:"email" gorm:"primaryKey"`
+	Failures      int       `json:"failures"`
+	LockedUntil   time.Time `json:"locked_until"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	Version       int       `json:"version" gorm:"not null;default:1"`
+}
+
+func NewAccountLockout(email string) *AccountLockout {
+	return &AccountLockout{Email: NormalizeEmail(email)}
+}
+
+func (a *AccountLockout) RetryAfter(now time.Time) time.Duration {
+	if now.Before(a.LockedUntil) {
+		return a.LockedUntil.Sub(now)
+	}
+
+	return 0
+}
+
+// RegisterFailure counts a failed login and pushes LockedUntil forward. Old
+// failures are forgotten once a full lockout period passed without any new
+// one, and a served lockout starts the count again.
+func (a *AccountLockout) RegisterFailure(policy LoginThrottlePolicy, now time.Time) {
+	if policy.LockoutDuration > 0 && now.Sub(a.LastFailureAt) > policy.LockoutDuration {
+		a.Failures = 0
+	}
+
+	if policy.MaxFailures > 0 && a.Failures >= policy.MaxFailures {
+		a.Failures = 0
+	}
+
+	a.Failures++
+	a.LastFailureAt = now
+	a.LockedUntil = now.Add(policy.Delay(a.Failures))
+}
+
+// Attempt lets a credential check through unless the account is locked, and
+// then counts it as a failure right away, so concurrent guesses cannot all get
+// in before the first one fails. A successful check clears the lockout.
+func (a *AccountLockout) Attempt(policy LoginThrottlePolicy, now time.Time) bool {
+	if a.RetryAfter(now) > 0 {
+		return false
+	}
+
+	a.RegisterFailure(policy, now)
+	return true
+}
+
+// LoginAttempt records one credential check coming from an IP address, used
+// to enforce the per IP sliding window.
+type LoginAttempt struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	IP        string    `json:"ip" gorm:"index"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
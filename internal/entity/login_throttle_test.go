@@ -0,0 +1,76 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fakeLoginThrottlePolicy = entity.LoginThrottlePolicy{
+	MaxFailures:     5,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Second,
+	LockoutDuration: 15 * time.Minute,
+}
+
+func TestLoginThrottlePolicy_Delay(t *testing.T) {
+	assert.Equal(t, time.Duration(0), fakeLoginThrottlePolicy.Delay(0))
+	assert.Equal(t, time.Second, fakeLoginThrottlePolicy.Delay(1))
+	assert.Equal(t, 2*time.Second, fakeLoginThrottlePolicy.Delay(2))
+	assert.Equal(t, 4*time.Second, fakeLoginThrottlePolicy.Delay(3))
+	assert.Equal(t, 5*time.Second, fakeLoginThrottlePolicy.Delay(4))
+	assert.Equal(t, 15*time.Minute, fakeLoginThrottlePolicy.Delay(5))
+	assert.Equal(t, time.Duration(0), entity.LoginThrottlePolicy{}.Delay(10))
+}
+
+func TestAccountLockout_RegisterFailure(t *testing.T) {
+	lockout := entity.NewAccountLockout(" John@Doe.com")
+	now := time.Now()
+
+	assert.Equal(t, "john@doe.com", lockout.Email)
+	assert.Equal(t, time.Duration(0), lockout.RetryAfter(now))
+
+	lockout.RegisterFailure(fakeLoginThrottlePolicy, now)
+	assert.Equal(t, 1, lockout.Failures)
+	assert.Equal(t, time.Second, lockout.RetryAfter(now))
+
+	for i := 0; i < 4; i++ {
+		now = lockout.LockedUntil
+		lockout.RegisterFailure(fakeLoginThrottlePolicy, now)
+	}
+	assert.Equal(t, 5, lockout.Failures)
+	assert.Equal(t, 15*time.Minute, lockout.RetryAfter(now))
+
+	now = lockout.LockedUntil
+	lockout.RegisterFailure(fakeLoginThrottlePolicy, now)
+	assert.Equal(t, 1, lockout.Failures)
+}
+
+func TestAccountLockout_Attempt(t *testing.T) {
+	lockout := entity.NewAccountLockout(fakeUserEmail)
+	now := time.Now()
+
+	assert.True(t, lockout.Attempt(fakeLoginThrottlePolicy, now))
+	assert.Equal(t, 1, lockout.Failures)
+
+	assert.False(t, lockout.Attempt(fakeLoginThrottlePolicy, now))
+	assert.Equal(t, 1, lockout.Failures)
+
+	assert.True(t, lockout.Attempt(fakeLoginThrottlePolicy, lockout.LockedUntil))
+	assert.Equal(t, 2, lockout.Failures)
+}
+
+func TestAccountLockoutForgetsOldFailures(t *testing.T) {
+	lockout := entity.NewAccountLockout(fakeUserEmail)
+	now := time.Now()
+
+	lockout.RegisterFailure(fakeLoginThrottlePolicy, now)
+	lockout.RegisterFailure(fakeLoginThrottlePolicy, now.Add(time.Second))
+	assert.Equal(t, 2, lockout.Failures)
+
+	lockout.RegisterFailure(fakeLoginThrottlePolicy, now.Add(time.Hour))
+	assert.Equal(t, 1, lockout.Failures)
+}
@@ -0,0 +1,58 @@
+package entity_test
+
+import (
+	"testing"
+
+	"app/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOAuthClient(t *testing.T) {
+	c, secret, err := entity.NewOAuthClient(" Partner ", []entity.Scope{entity.ScopeProductsRead}, "admin-id")
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, c.ID)
+	assert.Equal(t, "Partner", c.Name)
+	assert.Equal(t, "products:read", c.Scopes)
+	assert.Equal(t, "admin-id", c.CreatedBy)
+	assert.NotEqual(t, secret, c.SecretHash)
+	assert.True(t, c.ValidateSecret(secret))
+	assert.False(t, c.ValidateSecret("wrong"))
+}
+
+func TestNewOAuthClientValidation(t *testing.T) {
+	_, _, err := entity.NewOAuthClient("", []entity.Scope{entity.ScopeProductsRead}, "")
+	assert.Equal(t, entity.ErrClientNameIsRequired, err)
+
+	_, _, err = entity.NewOAuthClient("Partner", nil, "")
+	assert.Equal(t, entity.ErrScopeIsRequired, err)
+
+	_, _, err = entity.NewOAuthClient("Partner", []entity.Scope{"admin"}, "")
+	assert.Equal(t, entity.ErrInvalidScope, err)
+}
+
+func TestOAuthClientRotateSecret(t *testing.T) {
+	c, old, _ := entity.NewOAuthClient("Partner", []entity.Scope{entity.ScopeProductsRead}, "")
+
+	secret, err := c.RotateSecret()
+	assert.Nil(t, err)
+	assert.True(t, c.ValidateSecret(secret))
+	assert.False(t, c.ValidateSecret(old))
+}
+
+func TestOAuthClientGrantScopes(t *testing.T) {
+	c, _, _ := entity.NewOAuthClient("Partner", []entity.Scope{entity.ScopeProductsRead, entity.ScopeProductsWrite}, "")
+
+	granted, err := c.GrantScopes("")
+	assert.Nil(t, err)
+	assert.Equal(t, "products:read products:write", granted)
+
+	granted, err = c.GrantScopes("products:read products:read")
+	assert.Nil(t, err)
+	assert.Equal(t, "products:read", granted)
+
+	readOnly, _, _ := entity.NewOAuthClient("Reader", []entity.Scope{entity.ScopeProductsRead}, "")
+	_, err = readOnly.GrantScopes("products:write")
+	assert.Equal(t, entity.ErrInvalidScope, err)
+}
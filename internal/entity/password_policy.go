@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+
+	"app/pkg/passhash"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxPasswordBytes is the longest password bcrypt accepts, anything after it
+// would be silently ignored.
+const MaxPasswordBytes = 72
+
+var (
+	ErrPasswordIsRequired  = errors.New("password is required")
+	ErrPasswordTooShort    = errors.New("password is too short")
+	ErrPasswordTooLong     = errors.New("password is too long")
+	ErrPasswordNeedsUpper  = errors.New("password must contain an uppercase letter")
+	ErrPasswordNeedsLower  = errors.New("password must contain a lowercase letter")
+	ErrPasswordNeedsDigit  = errors.New("password must contain a digit")
+	ErrPasswordNeedsSymbol = errors.New("password must contain a symbol")
+)
+
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:    8,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+	}
+}
+
+var passwordPolicy = DefaultPasswordPolicy()
+
+// SetPasswordPolicy replaces the policy enforced by NewUser and
+// User.ChangePassword.
+func SetPasswordPolicy(policy PasswordPolicy) {
+	passwordPolicy = policy
+}
+
+var passwordHasher passhash.Hasher = &passhash.Bcrypt{Cost: bcrypt.DefaultCost}
+
+// SetPasswordHasher replaces the hasher of new passwords. Hashes of other
+// algorithms are still verified and upgraded on the next login.
+func SetPasswordHasher(hasher passhash.Hasher) {
+	passwordHasher = hasher
+}
+
+func (p PasswordPolicy) Validate(password string) error {
+	if password == "" {
+		return ErrPasswordIsRequired
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("%w: at least %d characters", ErrPasswordTooShort, p.MinLength)
+	}
+
+	if len(password) > MaxPasswordBytes {
+		return fmt.Errorf("%w: at most %d bytes", ErrPasswordTooLong, MaxPasswordBytes)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		return ErrPasswordNeedsUpper
+	}
+
+	if p.RequireLower && !hasLower {
+		return ErrPasswordNeedsLower
+	}
+
+	if p.RequireDigit && !hasDigit {
+		return ErrPasswordNeedsDigit
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		return ErrPasswordNeedsSymbol
+	}
+
+	return nil
+}
+
+// passwordFieldError reports a password rejected by the policy as a failure of
+// the password field, named after the rule it broke.
+func passwordFieldError(err error) *FieldError {
+	rule := "policy"
+	switch {
+	case errors.Is(err, ErrPasswordIsRequired):
+		rule = "required"
+	case errors.Is(err, ErrPasswordTooShort):
+		rule = "min_length"
+	case errors.Is(err, ErrPasswordTooLong):
+		rule = "max_length"
+	case errors.Is(err, ErrPasswordNeedsUpper):
+		rule = "uppercase"
+	case errors.Is(err, ErrPasswordNeedsLower):
+		rule = "lowercase"
+	case errors.Is(err, ErrPasswordNeedsDigit):
+		rule = "digit"
+	case errors.Is(err, ErrPasswordNeedsSymbol):
+		rule = "symbol"
+	}
+
+	return &FieldError{"password", rule, err}
+}
@@ -0,0 +1,36 @@
+package entity_test
+
+import (
+	"strings"
+	"testing"
+
+	"app/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := entity.PasswordPolicy{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	assert.Nil(t, policy.Validate("@Pass123"))
+	assert.Equal(t, entity.ErrPasswordIsRequired, policy.Validate(""))
+	assert.ErrorIs(t, policy.Validate("@Pa1"), entity.ErrPasswordTooShort)
+	assert.ErrorIs(t, policy.Validate("@Pa1"+strings.Repeat("a", 69)), entity.ErrPasswordTooLong)
+	assert.Equal(t, entity.ErrPasswordNeedsUpper, policy.Validate("@pass123"))
+	assert.Equal(t, entity.ErrPasswordNeedsLower, policy.Validate("@PASS123"))
+	assert.Equal(t, entity.ErrPasswordNeedsDigit, policy.Validate("@Password"))
+	assert.Equal(t, entity.ErrPasswordNeedsSymbol, policy.Validate("Pass1234"))
+}
+
+func TestPasswordPolicyCountsCharactersForMinLength(t *testing.T) {
+	policy := entity.PasswordPolicy{MinLength: 4}
+
+	assert.ErrorIs(t, policy.Validate("äöü"), entity.ErrPasswordTooShort)
+	assert.Nil(t, policy.Validate("äöüß"))
+}
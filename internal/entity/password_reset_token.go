@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+
+	"app/pkg/entity"
+)
+
+type PasswordResetToken struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewPasswordResetToken returns a single use reset token for the user and the
+// plain token string to be mailed, only its hash is persisted.
+func NewPasswordResetToken(userID string, expiresIn time.Duration) (*PasswordResetToken, string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+
+	return &PasswordResetToken{
+		ID:        entity.NewID().String(),
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		ExpiresAt: now.Add(expiresIn),
+		CreatedAt: now,
+	}, token, nil
+}
+
+func (t *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *PasswordResetToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
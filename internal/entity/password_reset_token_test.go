@@ -0,0 +1,29 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPasswordResetToken(t *testing.T) {
+	prt, token, err := entity.NewPasswordResetToken("user-id", time.Hour)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, prt)
+	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, prt.ID)
+	assert.Equal(t, "user-id", prt.UserID)
+	assert.Equal(t, entity.HashToken(token), prt.TokenHash)
+	assert.False(t, prt.IsExpired())
+	assert.False(t, prt.IsUsed())
+}
+
+func TestPasswordResetTokenIsExpired(t *testing.T) {
+	prt, _, _ := entity.NewPasswordResetToken("user-id", -time.Second)
+
+	assert.True(t, prt.IsExpired())
+}
@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"app/pkg/entity"
+	"app/pkg/money"
+
+	"gorm.io/gorm"
 )
 
 var (
@@ -16,17 +19,29 @@ var (
 )
 
 type Product struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Price     money.Money `json:"price" gorm:"-" swaggertype:"object,string" example:"amount:197.98,currency:USD"`
+	OwnerID   string      `json:"owner_id" gorm:"index"`
+	Version   int         `json:"version" gorm:"not null;default:1"`
+	CreatedAt time.Time   `json:"created_at"`
+
+	// PriceAmount and PriceCurrency store Price, in minor units so the
+	// database can sort and sum prices. They are kept in sync by the hooks.
+	PriceAmount   int64  `json:"-" gorm:"not null;default:0"`
+	PriceCurrency string `json:"-" gorm:"size:3"`
+
+	// DeletedAt is when the product was moved to the trash, and null while it
+	// is not deleted.
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
 }
 
-func NewProduct(name string, price float64) (*Product, error) {
+func NewProduct(name string, price money.Money) (*Product, error) {
 	p := &Product{
 		ID:        entity.NewID().String(),
 		Name:      name,
 		Price:     price,
+		Version:   1,
 		CreatedAt: time.Now(),
 	}
 
@@ -37,26 +52,57 @@ func NewProduct(name string, price float64) (*Product, error) {
 	return p, nil
 }
 
-func (p *Product) Validate() error {
-	if p.ID == "" {
-		return ErrIdIsRequired
-	}
+// BeforeSave copies Price into the columns storing it.
+func (p *Product) BeforeSave(tx *gorm.DB) error {
+	p.PriceAmount = p.Price.Amount()
+	p.PriceCurrency = p.Price.Currency()
+	return nil
+}
 
-	if _, err := entity.ParseID(string(p.ID)); err != nil {
+// AfterFind rebuilds Price from the columns storing it.
+func (p *Product) AfterFind(tx *gorm.DB) error {
+	if p.PriceCurrency == "" {
+		p.Price = money.Money{}
+		return nil
+	}
 
+	price, err := money.New(p.PriceAmount, p.PriceCurrency)
+	if err != nil {
+		return err
 	}
 
-	if p.Name == "" {
-		return ErrNameIsRequired
+	p.Price = price
+	return nil
+}
+
+func (p *Product) IsDeleted() bool {
+	return p.DeletedAt.Valid
+}
+
+func (p *Product) IsOwnedBy(userID string) bool {
+	return p.OwnerID != "" && p.OwnerID == userID
+}
+
+// Validate checks every field and reports all the failing ones in a
+// *ValidationError.
+func (p *Product) Validate() error {
+	v := &ValidationError{}
+
+	if p.ID == "" {
+		v.add("id", "required", ErrIdIsRequired)
+	} else if _, err := entity.ParseID(p.ID); err != nil {
+		v.add("id", "uuid", ErrInvalidId)
 	}
 
-	if p.Price == 0 {
-		return ErrPriceIsRequired
+	if p.Name == "" {
+		v.add("name", "required", ErrNameIsRequired)
 	}
 
-	if p.Price < 0 {
-		return ErrInvalidIdPrice
+	if p.Price.Currency() == "" || p.Price.IsZero() {
+		v.add("price", "required", ErrPriceIsRequired)
+	} else if p.Price.IsNegative() {
+		v.add("price", "positive", ErrInvalidIdPrice)
 	}
 
-	return nil
+	return v.err()
 }
@@ -0,0 +1,101 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// RevisionAction is the kind of change that produced a product revision.
+type RevisionAction string
+
+const (
+	RevisionCreated  RevisionAction = "created"
+	RevisionUpdated  RevisionAction = "updated"
+	RevisionDeleted  RevisionAction = "deleted"
+	RevisionRestored RevisionAction = "restored"
+)
+
+// ProductRevision is an immutable snapshot of a product right after a change.
+// Its number is the version the change gave the product, so revision n holds
+// the product as it was at version n.
+type ProductRevision struct {
+	ProductID string         `json:"product_id" gorm:"primaryKey"`
+	Number    int            `json:"number" gorm:"primaryKey;autoIncrement:false"`
+	Action    RevisionAction `json:"action"`
+	ActorID   string         `json:"actor_id" gorm:"index"`
+	Snapshot  Product        `json:"snapshot" gorm:"serializer:json"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+func NewProductRevision(action RevisionAction, product *Product, actorID string) *ProductRevision {
+	return &ProductRevision{
+		ProductID: product.ID,
+		Number:    product.Version,
+		Action:    action,
+		ActorID:   actorID,
+		Snapshot:  *product,
+		CreatedAt: time.Now(),
+	}
+}
+
+// FieldChange is a product field holding different JSON values in two
+// revisions. A field missing from a revision is null.
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from" swaggertype:"object"`
+	To    json.RawMessage `json:"to" swaggertype:"object"`
+}
+
+// DiffProducts lists the fields that differ between two states of a product,
+// ordered by field name.
+func DiffProducts(from, to *Product) ([]FieldChange, error) {
+	before, err := jsonFields(from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := jsonFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(after))
+	for field := range after {
+		fields = append(fields, field)
+	}
+	for field := range before {
+		if _, ok := after[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []FieldChange{}
+	for _, field := range fields {
+		b, a := orNull(before[field]), orNull(after[field])
+		if !bytes.Equal(b, a) {
+			changes = append(changes, FieldChange{field, b, a})
+		}
+	}
+
+	return changes, nil
+}
+
+func jsonFields(p *Product) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	err = json.Unmarshal(raw, &fields)
+	return fields, err
+}
+
+func orNull(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
@@ -0,0 +1,46 @@
+package entity_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"app/internal/entity"
+	"app/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProductRevision(t *testing.T) {
+	p, _ := entity.NewProduct("Product 1", money.MustParse("10", "USD"))
+	p.Version = 3
+
+	r := entity.NewProductRevision(entity.RevisionUpdated, p, "user-id")
+
+	assert.Equal(t, p.ID, r.ProductID)
+	assert.Equal(t, 3, r.Number)
+	assert.Equal(t, entity.RevisionUpdated, r.Action)
+	assert.Equal(t, "user-id", r.ActorID)
+	assert.Equal(t, "Product 1", r.Snapshot.Name)
+	assert.False(t, r.CreatedAt.IsZero())
+
+	p.Name = "Product 2"
+	assert.Equal(t, "Product 1", r.Snapshot.Name)
+}
+
+func TestDiffProducts(t *testing.T) {
+	from, _ := entity.NewProduct("Product 1", money.MustParse("10", "USD"))
+	to := *from
+	to.Price = money.MustParse("12.5", "USD")
+	to.Version = 2
+
+	changes, err := entity.DiffProducts(from, &to)
+	assert.Nil(t, err)
+	assert.Equal(t, []entity.FieldChange{
+		{Field: "price", From: json.RawMessage(`{"amount":"10.00","currency":"USD"}`), To: json.RawMessage(`{"amount":"12.50","currency":"USD"}`)},
+		{Field: "version", From: json.RawMessage("1"), To: json.RawMessage("2")},
+	}, changes)
+
+	changes, err = entity.DiffProducts(from, from)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+}
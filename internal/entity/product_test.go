@@ -4,13 +4,14 @@ import (
 	"testing"
 
 	"app/internal/entity"
+	"app/pkg/money"
 
 	"github.com/stretchr/testify/assert"
 )
 
 var (
 	fakeProductName  = "Product Test"
-	fakeProductPrice = 10.0
+	fakeProductPrice = money.MustParse("10.00", "USD")
 )
 
 func TestNewProduct(t *testing.T) {
@@ -26,19 +27,50 @@ func TestProductWhenNameIsRequired(t *testing.T) {
 	p, err := entity.NewProduct("", fakeProductPrice)
 	assert.NotNil(t, err)
 	assert.Nil(t, p)
-	assert.Equal(t, entity.ErrNameIsRequired, err)
+	assert.ErrorIs(t, err, entity.ErrNameIsRequired)
 }
 
 func TestProductWhenPriceIsRequired(t *testing.T) {
-	p, err := entity.NewProduct(fakeProductName, 0)
+	p, err := entity.NewProduct(fakeProductName, money.Money{})
 	assert.NotNil(t, err)
 	assert.Nil(t, p)
-	assert.Equal(t, entity.ErrPriceIsRequired, err)
+	assert.ErrorIs(t, err, entity.ErrPriceIsRequired)
 }
 
 func TestProductWhenPriceIsInvalid(t *testing.T) {
-	p, err := entity.NewProduct(fakeProductName, -1)
+	p, err := entity.NewProduct(fakeProductName, money.MustParse("-1", "USD"))
 	assert.NotNil(t, err)
 	assert.Nil(t, p)
-	assert.Equal(t, entity.ErrInvalidIdPrice, err)
+	assert.ErrorIs(t, err, entity.ErrInvalidIdPrice)
+}
+
+func TestProductReportsEveryInvalidField(t *testing.T) {
+	p, err := entity.NewProduct("", money.MustParse("-1", "USD"))
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, entity.ErrNameIsRequired)
+	assert.ErrorIs(t, err, entity.ErrInvalidIdPrice)
+
+	fields := entity.FieldErrors(err)
+	assert.Len(t, fields, 2)
+	assert.Equal(t, "name", fields[0].Field)
+	assert.Equal(t, "required", fields[0].Rule)
+	assert.Equal(t, "price", fields[1].Field)
+	assert.Equal(t, "positive", fields[1].Rule)
+	assert.Equal(t, "name is required; price is invalid", err.Error())
+}
+
+func TestProductWhenIdIsInvalid(t *testing.T) {
+	p, _ := entity.NewProduct(fakeProductName, fakeProductPrice)
+
+	p.ID = "not-a-uuid"
+	assert.ErrorIs(t, p.Validate(), entity.ErrInvalidId)
+}
+
+func TestProductWhenOwnedByUser(t *testing.T) {
+	p, _ := entity.NewProduct(fakeProductName, fakeProductPrice)
+	assert.False(t, p.IsOwnedBy(""))
+
+	p.OwnerID = "user-id"
+	assert.True(t, p.IsOwnedBy("user-id"))
+	assert.False(t, p.IsOwnedBy("other-user-id"))
 }
@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"app/pkg/entity"
+)
+
+// RecoveryCodeCount is how many one time codes are issued when two-factor
+// authentication is enabled.
+const RecoveryCodeCount = 10
+
+type RecoveryCode struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id" gorm:"index"`
+	CodeHash  string     `json:"-" gorm:"index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewRecoveryCodes returns RecoveryCodeCount hashed codes for the user and
+// their plain values, formatted as xxxx-xxxx-xxxx-xxxx, to show only once.
+func NewRecoveryCodes(userID string) ([]*RecoveryCode, []string, error) {
+	codes := make([]*RecoveryCode, RecoveryCodeCount)
+	plains := make([]string, RecoveryCodeCount)
+	now := time.Now()
+
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+
+		encoded := strings.ToLower(base32.StdEncoding.EncodeToString(raw))
+		plains[i] = encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16]
+
+		codes[i] = &RecoveryCode{
+			ID:        entity.NewID().String(),
+			UserID:    userID,
+			CodeHash:  HashRecoveryCode(plains[i]),
+			CreatedAt: now,
+		}
+	}
+
+	return codes, plains, nil
+}
+
+// HashRecoveryCode ignores case, spaces and dashes so codes can be typed back
+// loosely.
+func HashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return HashToken(normalized)
+}
@@ -0,0 +1,34 @@
+package entity_test
+
+import (
+	"strings"
+	"testing"
+
+	"app/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, plains, err := entity.NewRecoveryCodes("user-id")
+
+	assert.Nil(t, err)
+	assert.Len(t, codes, entity.RecoveryCodeCount)
+	assert.Len(t, plains, entity.RecoveryCodeCount)
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		assert.Equal(t, "user-id", code.UserID)
+		assert.Len(t, plains[i], 19)
+		assert.Equal(t, entity.HashRecoveryCode(plains[i]), code.CodeHash)
+		assert.False(t, seen[plains[i]])
+		seen[plains[i]] = true
+	}
+}
+
+func TestHashRecoveryCodeIsLoose(t *testing.T) {
+	assert.Equal(t,
+		entity.HashRecoveryCode("abcd-efgh-ijkl-mnop"),
+		entity.HashRecoveryCode(" ABCD EFGH "+strings.ToUpper("ijklmnop")),
+	)
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"time"
+
+	"app/pkg/entity"
+)
+
+type RefreshToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id" gorm:"index"`
+	FamilyID   string     `json:"family_id" gorm:"index"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`
+	ReplacedBy string     `json:"replaced_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewRefreshToken issues a refresh token for the given user and returns it
+// together with the plain token string, which is never persisted. An empty
+// familyID starts a new token family.
+func NewRefreshToken(userID, familyID string, expiresIn time.Duration) (*RefreshToken, string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if familyID == "" {
+		familyID = entity.NewID().String()
+	}
+
+	now := time.Now()
+
+	return &RefreshToken{
+		ID:        entity.NewID().String(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: HashToken(token),
+		ExpiresAt: now.Add(expiresIn),
+		CreatedAt: now,
+	}, token, nil
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
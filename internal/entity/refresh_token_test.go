@@ -0,0 +1,40 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	rt, token, err := entity.NewRefreshToken("user-id", "", time.Hour)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, rt)
+	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, rt.ID)
+	assert.NotEmpty(t, rt.FamilyID)
+	assert.Equal(t, "user-id", rt.UserID)
+	assert.Equal(t, entity.HashToken(token), rt.TokenHash)
+	assert.NotEqual(t, token, rt.TokenHash)
+	assert.False(t, rt.IsExpired())
+	assert.False(t, rt.IsRevoked())
+}
+
+func TestNewRefreshTokenKeepsFamily(t *testing.T) {
+	first, _, _ := entity.NewRefreshToken("user-id", "", time.Hour)
+	second, _, err := entity.NewRefreshToken("user-id", first.FamilyID, time.Hour)
+
+	assert.Nil(t, err)
+	assert.Equal(t, first.FamilyID, second.FamilyID)
+	assert.NotEqual(t, first.TokenHash, second.TokenHash)
+}
+
+func TestRefreshTokenIsExpired(t *testing.T) {
+	rt, _, _ := entity.NewRefreshToken("user-id", "", -time.Second)
+
+	assert.True(t, rt.IsExpired())
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// RevokedToken marks a single access token, identified by its jti claim, as
+// no longer valid. It only needs to be kept until the token expires.
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserTokenRevocation invalidates every access token of a user issued up to
+// RevokedBefore, kept to the millisecond like the iat_ms claim.
+type UserTokenRevocation struct {
+	UserID        string    `json:"user_id" gorm:"primaryKey"`
+	RevokedBefore time.Time `json:"revoked_before"`
+}
+
+func NewRevokedToken(jti, userID string, expiresAt time.Time) *RevokedToken {
+	return &RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (t *RevokedToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrScopeIsRequired = errors.New("at least one scope is required")
+	ErrInvalidScope    = errors.New("invalid scope")
+)
+
+// Scope limits what a machine credential, an API key or an OAuth client, may
+// do. Scopes are stored and sent space separated, as in OAuth 2.0.
+type Scope string
+
+const (
+	ScopeProductsRead  Scope = "products:read"
+	ScopeProductsWrite Scope = "products:write"
+)
+
+func (s Scope) IsValid() bool {
+	switch s {
+	case ScopeProductsRead, ScopeProductsWrite:
+		return true
+	}
+	return false
+}
+
+// JoinScopes validates the scopes and joins them, dropping duplicates.
+func JoinScopes(scopes []Scope) (string, error) {
+	if len(scopes) == 0 {
+		return "", ErrScopeIsRequired
+	}
+
+	names := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !s.IsValid() {
+			return "", ErrInvalidScope
+		}
+		if !HasScope(strings.Join(names, " "), s) {
+			names = append(names, string(s))
+		}
+	}
+
+	return strings.Join(names, " "), nil
+}
+
+func SplitScopes(scopes string) []Scope {
+	var list []Scope
+	for _, s := range strings.Fields(scopes) {
+		list = append(list, Scope(s))
+	}
+	return list
+}
+
+func HasScope(scopes string, scope Scope) bool {
+	for _, s := range strings.Fields(scopes) {
+		if Scope(s) == scope {
+			return true
+		}
+	}
+	return false
+}
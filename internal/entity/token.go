@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// newOpaqueToken returns a random URL safe token with 256 bits of entropy.
+func newOpaqueToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// HashToken returns the hex encoded SHA-256 digest used to store and look up
+// opaque tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Values of the "typ" claim, telling apart access tokens from tokens that are
+// only accepted by a specific endpoint. API key requests get an in-memory
+// token of type TokenTypeAPIKey, OAuth clients get tokens of TokenTypeClient.
+const (
+	TokenTypeAccess       = "access"
+	TokenTypeMfaChallenge = "mfa_challenge"
+	TokenTypeAPIKey       = "api_key"
+	TokenTypeClient       = "client"
+)
+
+// TokenIssuedAt returns the issue time of a token from its iat_ms claim, in
+// milliseconds, or its iat claim for tokens issued without one. The whole
+// seconds of iat are too coarse to tell a token issued just before a
+// revoke-all from one issued just after.
+func TokenIssuedAt(iat time.Time, claims map[string]interface{}) time.Time {
+	switch ms := claims["iat_ms"].(type) {
+	case int64:
+		return time.UnixMilli(ms)
+	case float64:
+		return time.UnixMilli(int64(ms))
+	case json.Number:
+		if n, err := ms.Int64(); err == nil {
+			return time.UnixMilli(n)
+		}
+	}
+
+	return iat
+}
@@ -0,0 +1,22 @@
+package entity_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"app/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenIssuedAt(t *testing.T) {
+	iat := time.Unix(1700000000, 0)
+	issuedAt := time.UnixMilli(1700000000250)
+
+	assert.Equal(t, issuedAt, entity.TokenIssuedAt(iat, map[string]interface{}{"iat_ms": int64(1700000000250)}))
+	assert.Equal(t, issuedAt, entity.TokenIssuedAt(iat, map[string]interface{}{"iat_ms": float64(1700000000250)}))
+	assert.Equal(t, issuedAt, entity.TokenIssuedAt(iat, map[string]interface{}{"iat_ms": json.Number("1700000000250")}))
+	assert.Equal(t, iat, entity.TokenIssuedAt(iat, map[string]interface{}{}))
+	assert.Equal(t, iat, entity.TokenIssuedAt(iat, nil))
+}
@@ -1,36 +1,286 @@
 package entity
 
 import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
 	"app/pkg/entity"
+	"app/pkg/passhash"
+	"app/pkg/totp"
+)
 
-	"golang.org/x/crypto/bcrypt"
+var (
+	ErrInvalidRole        = errors.New("role is invalid")
+	ErrEmailIsRequired    = errors.New("email is required")
+	ErrInvalidEmail       = errors.New("email is invalid")
+	ErrTotpAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTotpNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrInvalidTotpCode    = errors.New("two-factor code is invalid")
 )
 
+// totpSkew is the number of 30 second steps accepted before and after the
+// current one, to tolerate clock drift on the user's device.
+const totpSkew = 1
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes reports whether r grants at least the permissions of other.
+func (r Role) Includes(other Role) bool {
+	return r.IsValid() && roleRanks[r] >= roleRanks[other]
+}
+
 type User struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
-	Email    string `json:"email"`
+	Email    string `json:"email" gorm:"index:idx_users_email,unique,expression:lower(email)"`
 	Password string `json:"-"`
+	Role     Role   `json:"role" gorm:"default:viewer"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	TotpSecret       string `json:"-"`
+	TotpEnabled      bool   `json:"totp_enabled"`
+	TotpLastUsedStep int64  `json:"-"`
+
+	DisabledAt            *time.Time `json:"disabled_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+
+	// Version is incremented by every update, which only applies to the
+	// version it was read at.
+	Version int `json:"-" gorm:"not null;default:1"`
 }
 
 func NewUser(name, email, password string) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	u := &User{
+		ID:      entity.NewID().String(),
+		Name:    name,
+		Email:   NormalizeEmail(email),
+		Role:    RoleViewer,
+		Version: 1,
+	}
 
-	if err != nil {
+	v := u.validate()
+	if err := passwordPolicy.Validate(password); err != nil {
+		v.Fields = append(v.Fields, passwordFieldError(err))
+	}
+	if err := v.err(); err != nil {
 		return nil, err
 	}
 
-	return &User{
-		ID:       entity.NewID().String(),
-		Name:     name,
-		Email:    email,
-		Password: string(hashedPassword),
-	}, nil
+	if err := u.RehashPassword(password); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
 
+// Validate checks every field and reports all the failing ones in a
+// *ValidationError.
+func (u *User) Validate() error {
+	return u.validate().err()
+}
+
+func (u *User) validate() *ValidationError {
+	v := &ValidationError{}
+
+	if strings.TrimSpace(u.Name) == "" {
+		v.add("name", "required", ErrNameIsRequired)
+	}
+
+	if u.Email == "" {
+		v.add("email", "required", ErrEmailIsRequired)
+	} else if !isValidEmail(u.Email) {
+		v.add("email", "email", ErrInvalidEmail)
+	}
+
+	if !u.Role.IsValid() {
+		v.add("role", "one_of", ErrInvalidRole)
+	}
+
+	return v
+}
+
+// isValidEmail accepts a bare RFC 5322 address, without display name or
+// comments, whose domain has at least two labels.
+func isValidEmail(email string) bool {
+	if len(email) > 254 {
+		return false
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return false
+	}
+
+	at := strings.LastIndex(email, "@")
+	domain := email[at+1:]
+
+	return strings.Contains(domain, ".") &&
+		!strings.HasPrefix(domain, ".") &&
+		!strings.HasSuffix(domain, ".")
+}
+
+// NormalizeEmail trims and lowercases an email so the same address is always
+// stored and looked up the same way.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (u *User) ChangePassword(password string) error {
+	if err := passwordPolicy.Validate(password); err != nil {
+		return passwordFieldError(err)
+	}
+
+	if err := u.RehashPassword(password); err != nil {
+		return err
+	}
+
+	u.PasswordResetRequired = false
+	return nil
+}
+
+// RehashPassword stores a new hash of the password made by the current hasher.
+// It skips the policy, a password already accepted at login is kept as is.
+func (u *User) RehashPassword(password string) error {
+	hashedPassword, err := passwordHasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	u.Password = hashedPassword
+	return nil
 }
 
 func (u *User) ValidatePassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+	ok, err := passhash.Verify(password, u.Password)
+
+	return err == nil && ok
+}
+
+// PasswordNeedsRehash reports whether the stored hash is weaker than the one
+// the current hasher would make.
+func (u *User) PasswordNeedsRehash() bool {
+	return passwordHasher.NeedsRehash(u.Password)
+}
+
+// RequirePasswordReset blocks logins with the current password until a new
+// one is chosen.
+func (u *User) RequirePasswordReset() {
+	u.PasswordResetRequired = true
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u *User) Disable() {
+	if u.DisabledAt != nil {
+		return
+	}
+
+	now := time.Now()
+	u.DisabledAt = &now
+}
+
+func (u *User) Enable() {
+	u.DisabledAt = nil
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) VerifyEmail() {
+	if u.EmailVerifiedAt != nil {
+		return
+	}
+
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+}
+
+// EnrollTotp generates a new secret that only becomes active once a code
+// generated from it is confirmed.
+func (u *User) EnrollTotp() (string, error) {
+	if u.TotpEnabled {
+		return "", ErrTotpAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	u.TotpSecret = secret
+	u.TotpLastUsedStep = 0
+	return secret, nil
+}
+
+func (u *User) ConfirmTotp(code string, now time.Time) error {
+	if u.TotpEnabled {
+		return ErrTotpAlreadyEnabled
+	}
+
+	if u.TotpSecret == "" {
+		return ErrTotpNotEnrolled
+	}
+
+	step, ok := totp.Validate(u.TotpSecret, code, now, totpSkew)
+	if !ok {
+		return ErrInvalidTotpCode
+	}
+
+	u.TotpEnabled = true
+	u.TotpLastUsedStep = step
+	return nil
+}
+
+// VerifyTotp checks a code of an enabled secret. A code is accepted only once,
+// codes of the same or an earlier time step are rejected afterwards.
+func (u *User) VerifyTotp(code string, now time.Time) bool {
+	if !u.TotpEnabled {
+		return false
+	}
+
+	step, ok := totp.Validate(u.TotpSecret, code, now, totpSkew)
+	if !ok || step <= u.TotpLastUsedStep {
+		return false
+	}
+
+	u.TotpLastUsedStep = step
+	return true
+}
+
+func (u *User) DisableTotp() {
+	u.TotpSecret = ""
+	u.TotpEnabled = false
+	u.TotpLastUsedStep = 0
+}
+
+func (u *User) SetRole(role Role) error {
+	if !role.IsValid() {
+		return &FieldError{"role", "one_of", ErrInvalidRole}
+	}
 
-	return err == nil
+	u.Role = role
+	return nil
 }
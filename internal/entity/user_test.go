@@ -2,8 +2,11 @@ package entity_test
 
 import (
 	"testing"
+	"time"
 
 	"app/internal/entity"
+	"app/pkg/passhash"
+	"app/pkg/totp"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -25,6 +28,62 @@ func TestNewUser(t *testing.T) {
 	assert.NotEmpty(t, user.Password)
 }
 
+func TestNewUserNormalizesEmail(t *testing.T) {
+	user, err := entity.NewUser(fakeUserName, "  John@Doe.COM ", fakeUserPassword)
+
+	assert.Nil(t, err)
+	assert.Equal(t, fakeUserEmail, user.Email)
+}
+
+func TestUserWhenNameIsRequired(t *testing.T) {
+	user, err := entity.NewUser(" ", fakeUserEmail, fakeUserPassword)
+
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, entity.ErrNameIsRequired)
+}
+
+func TestUserWhenEmailIsRequired(t *testing.T) {
+	user, err := entity.NewUser(fakeUserName, "", fakeUserPassword)
+
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, entity.ErrEmailIsRequired)
+}
+
+func TestUserWhenEmailIsInvalid(t *testing.T) {
+	for _, email := range []string{
+		"john",
+		"john@",
+		"@doe.com",
+		"john@doe",
+		"john@doe.",
+		"John Doe <john@doe.com>",
+		"john doe@doe.com",
+	} {
+		user, err := entity.NewUser(fakeUserName, email, fakeUserPassword)
+
+		assert.Nil(t, user, email)
+		assert.ErrorIs(t, err, entity.ErrInvalidEmail, email)
+	}
+}
+
+func TestUserWhenPasswordBreaksPolicy(t *testing.T) {
+	user, err := entity.NewUser(fakeUserName, fakeUserEmail, "short")
+
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, entity.ErrPasswordTooShort)
+}
+
+func TestNewUserReportsEveryInvalidField(t *testing.T) {
+	user, err := entity.NewUser("", "john", "short")
+
+	assert.Nil(t, user)
+	fields := entity.FieldErrors(err)
+	assert.Len(t, fields, 3)
+	assert.Equal(t, [2]string{"name", "required"}, [2]string{fields[0].Field, fields[0].Rule})
+	assert.Equal(t, [2]string{"email", "email"}, [2]string{fields[1].Field, fields[1].Rule})
+	assert.Equal(t, [2]string{"password", "min_length"}, [2]string{fields[2].Field, fields[2].Rule})
+}
+
 func TestUser_ValidatePassword(t *testing.T) {
 	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPassword)
 
@@ -32,3 +91,131 @@ func TestUser_ValidatePassword(t *testing.T) {
 	assert.False(t, user.ValidatePassword("wrongPassword"))
 	assert.NotEqual(t, fakeUserPassword, user.Password)
 }
+
+func TestUser_ChangePassword(t *testing.T) {
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPassword)
+	previous := user.Password
+
+	err := user.ChangePassword("@NewPass123")
+	assert.Nil(t, err)
+	assert.NotEqual(t, previous, user.Password)
+	assert.True(t, user.ValidatePassword("@NewPass123"))
+	assert.False(t, user.ValidatePassword(fakeUserPassword))
+
+	err = user.ChangePassword("nouppercase1")
+	assert.ErrorIs(t, err, entity.ErrPasswordNeedsUpper)
+	assert.Equal(t, "uppercase", entity.FieldErrors(err)[0].Rule)
+	assert.True(t, user.ValidatePassword("@NewPass123"))
+}
+
+func TestUser_PasswordNeedsRehash(t *testing.T) {
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPassword)
+	assert.False(t, user.PasswordNeedsRehash())
+
+	entity.SetPasswordHasher(passhash.NewArgon2id(passhash.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1}))
+	defer entity.SetPasswordHasher(&passhash.Bcrypt{Cost: 10})
+
+	assert.True(t, user.PasswordNeedsRehash())
+	assert.True(t, user.ValidatePassword(fakeUserPassword))
+
+	assert.Nil(t, user.RehashPassword(fakeUserPassword))
+	assert.False(t, user.PasswordNeedsRehash())
+	assert.True(t, user.ValidatePassword(fakeUserPassword))
+}
+
+func TestUser_Disable(t *testing.T) {
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPassword)
+	assert.False(t, user.IsDisabled())
+
+	user.Disable()
+	assert.True(t, user.IsDisabled())
+	disabledAt := user.DisabledAt
+
+	user.Disable()
+	assert.Equal(t, disabledAt, user.DisabledAt)
+
+	user.Enable()
+	assert.False(t, user.IsDisabled())
+}
+
+func TestUser_RequirePasswordReset(t *testing.T) {
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPassword)
+	assert.False(t, user.PasswordResetRequired)
+
+	user.RequirePasswordReset()
+	assert.True(t, user.PasswordResetRequired)
+
+	assert.Nil(t, user.RehashPassword(fakeUserPassword))
+	assert.True(t, user.PasswordResetRequired)
+
+	assert.Nil(t, user.ChangePassword("@NewPass123"))
+	assert.False(t, user.PasswordResetRequired)
+}
+
+func TestNewUserIsViewer(t *testing.T) {
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPassword)
+
+	assert.Equal(t, entity.RoleViewer, user.Role)
+}
+
+func TestUser_SetRole(t *testing.T) {
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPassword)
+
+	assert.Nil(t, user.SetRole(entity.RoleAdmin))
+	assert.Equal(t, entity.RoleAdmin, user.Role)
+	assert.ErrorIs(t, user.SetRole("root"), entity.ErrInvalidRole)
+	assert.Equal(t, entity.RoleAdmin, user.Role)
+}
+
+func TestRole_Includes(t *testing.T) {
+	assert.True(t, entity.RoleAdmin.Includes(entity.RoleEditor))
+	assert.True(t, entity.RoleEditor.Includes(entity.RoleEditor))
+	assert.True(t, entity.RoleEditor.Includes(entity.RoleViewer))
+	assert.False(t, entity.RoleViewer.Includes(entity.RoleEditor))
+	assert.False(t, entity.Role("").Includes(entity.RoleViewer))
+}
+
+func TestUser_VerifyEmail(t *testing.T) {
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPassword)
+	assert.False(t, user.IsEmailVerified())
+
+	user.VerifyEmail()
+	assert.True(t, user.IsEmailVerified())
+
+	verifiedAt := *user.EmailVerifiedAt
+	user.VerifyEmail()
+	assert.Equal(t, verifiedAt, *user.EmailVerifiedAt)
+}
+
+func TestUser_Totp(t *testing.T) {
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPassword)
+	now := time.Now()
+
+	assert.Equal(t, entity.ErrTotpNotEnrolled, user.ConfirmTotp("123456", now))
+
+	secret, err := user.EnrollTotp()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, secret)
+	assert.False(t, user.TotpEnabled)
+	assert.False(t, user.VerifyTotp("123456", now))
+
+	assert.Equal(t, entity.ErrInvalidTotpCode, user.ConfirmTotp("000000x", now))
+
+	code, _ := totp.Code(secret, totp.Step(now))
+	assert.Nil(t, user.ConfirmTotp(code, now))
+	assert.True(t, user.TotpEnabled)
+
+	_, err = user.EnrollTotp()
+	assert.Equal(t, entity.ErrTotpAlreadyEnabled, err)
+
+	assert.False(t, user.VerifyTotp(code, now), "code used to confirm can not be replayed")
+
+	next := now.Add(totp.Period * time.Second)
+	code, _ = totp.Code(secret, totp.Step(next))
+	assert.True(t, user.VerifyTotp(code, next))
+	assert.False(t, user.VerifyTotp(code, next))
+
+	user.DisableTotp()
+	assert.False(t, user.TotpEnabled)
+	assert.Empty(t, user.TotpSecret)
+}
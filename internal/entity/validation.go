@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+)
+
+// FieldError is a field breaking one of its validation rules. It unwraps to
+// the sentinel error of the rule, such as ErrNameIsRequired.
+type FieldError struct {
+	Field string
+	Rule  string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every field of an entity failing validation, so they
+// can all be reported at once. errors.Is matches the error of any field.
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+func (e *ValidationError) add(field, rule string, err error) {
+	e.Fields = append(e.Fields, &FieldError{field, rule, err})
+}
+
+// err returns nil when no field failed, and e otherwise.
+func (e *ValidationError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// FieldErrors returns the failing fields of a validation error, which is
+// either a *ValidationError or a single *FieldError. It returns nil for any
+// other error.
+func FieldErrors(err error) []*FieldError {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.Fields
+	}
+
+	var ferr *FieldError
+	if errors.As(err, &ferr) {
+		return []*FieldError{ferr}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"time"
+
+	"app/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type APIKey struct {
+	DB *gorm.DB
+}
+
+func NewAPIKey(db *gorm.DB) *APIKey {
+	return &APIKey{DB: db}
+}
+
+func (a *APIKey) Create(key *entity.APIKey) error {
+	return translateError(a.DB, a.DB.Create(key).Error)
+}
+
+func (a *APIKey) FindByHash(hash string) (*entity.APIKey, error) {
+	key := &entity.APIKey{}
+	err := a.DB.Where("key_hash = ?", hash).First(key).Error
+	return key, translateError(a.DB, err)
+}
+
+// FindAllByUser returns every key of the user, revoked ones included, newest
+// first.
+func (a *APIKey) FindAllByUser(userID string) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+	err := a.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&keys).Error
+	return keys, translateError(a.DB, err)
+}
+
+// Revoke revokes a key of the user. It fails with ErrNotFound when the user
+// has no such active key.
+func (a *APIKey) Revoke(id, userID string) error {
+	result := a.DB.Model(&entity.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return translateError(a.DB, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (a *APIKey) TouchLastUsed(id string, at time.Time) error {
+	err := a.DB.Model(&entity.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+	return translateError(a.DB, err)
+}
@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeInMemoryAPIKeyDB(t *testing.T) *database.APIKey {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.APIKey{})
+
+	return database.NewAPIKey(db)
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	keyDB := makeInMemoryAPIKeyDB(t)
+
+	k, key, err := entity.NewAPIKey("user-id", "job", []entity.Scope{entity.ScopeProductsRead}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, keyDB.Create(k))
+
+	found, err := keyDB.FindByHash(entity.HashToken(key))
+	assert.Nil(t, err)
+	assert.Equal(t, k.ID, found.ID)
+	assert.Equal(t, k.Scopes, found.Scopes)
+
+	keys, err := keyDB.FindAllByUser("user-id")
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+
+	keys, err = keyDB.FindAllByUser("other-user")
+	assert.Nil(t, err)
+	assert.Len(t, keys, 0)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	keyDB := makeInMemoryAPIKeyDB(t)
+
+	k, key, _ := entity.NewAPIKey("user-id", "job", []entity.Scope{entity.ScopeProductsRead}, nil)
+	assert.Nil(t, keyDB.Create(k))
+
+	assert.ErrorIs(t, keyDB.Revoke(k.ID, "other-user"), database.ErrNotFound)
+	assert.Nil(t, keyDB.Revoke(k.ID, "user-id"))
+	assert.ErrorIs(t, keyDB.Revoke(k.ID, "user-id"), database.ErrNotFound)
+
+	found, err := keyDB.FindByHash(entity.HashToken(key))
+	assert.Nil(t, err)
+	assert.True(t, found.IsRevoked())
+}
+
+func TestTouchAPIKeyLastUsed(t *testing.T) {
+	keyDB := makeInMemoryAPIKeyDB(t)
+
+	k, key, _ := entity.NewAPIKey("user-id", "job", []entity.Scope{entity.ScopeProductsRead}, nil)
+	assert.Nil(t, keyDB.Create(k))
+
+	now := time.Now()
+	assert.Nil(t, keyDB.TouchLastUsed(k.ID, now))
+
+	found, _ := keyDB.FindByHash(entity.HashToken(key))
+	assert.NotNil(t, found.LastUsedAt)
+	assert.WithinDuration(t, now, *found.LastUsedAt, time.Second)
+}
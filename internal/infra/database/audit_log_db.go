@@ -0,0 +1,66 @@
+package database
+
+import (
+	"time"
+
+	"app/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+// AuditEventFilter narrows an audit log query. Empty fields match everything.
+type AuditEventFilter struct {
+	Action       entity.AuditAction
+	ActorID      string
+	ResourceType string
+	ResourceID   string
+	From         *time.Time
+	To           *time.Time
+}
+
+// AuditLog is append-only: events can be added and read, never changed.
+type AuditLog struct {
+	DB *gorm.DB
+}
+
+func NewAuditLog(db *gorm.DB) *AuditLog {
+	return &AuditLog{DB: db}
+}
+
+func (a *AuditLog) Append(event *entity.AuditEvent) error {
+	return translateError(a.DB, a.DB.Create(event).Error)
+}
+
+// Find returns a page of the matching events, newest first, and how many
+// events match in total.
+func (a *AuditLog) Find(filter AuditEventFilter, page, limit int) ([]entity.AuditEvent, int64, error) {
+	query := a.DB.Model(&entity.AuditEvent{})
+
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		query = query.Where("resource_id = ?", filter.ResourceID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, translateError(a.DB, err)
+	}
+
+	var events []entity.AuditEvent
+	err := query.Order("created_at desc").Offset((page - 1) * limit).Limit(limit).Find(&events).Error
+	return events, total, translateError(a.DB, err)
+}
@@ -0,0 +1,76 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeInMemoryAuditLogDB(t *testing.T) *database.AuditLog {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.AuditEvent{})
+
+	return database.NewAuditLog(db)
+}
+
+func TestAppendAuditEvent(t *testing.T) {
+	auditDB := makeInMemoryAuditLogDB(t)
+
+	e := entity.NewAuditEvent(entity.AuditProductCreated, "product", "product-id")
+	e.ActorID = "user-id"
+	assert.Nil(t, e.SetSnapshots(nil, map[string]string{"name": "Product"}))
+	assert.Nil(t, auditDB.Append(e))
+
+	events, total, err := auditDB.Find(database.AuditEventFilter{}, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, e.ID, events[0].ID)
+	assert.JSONEq(t, `{"name":"Product"}`, string(events[0].After))
+	assert.Nil(t, events[0].Before)
+}
+
+func TestFindAuditEvents(t *testing.T) {
+	auditDB := makeInMemoryAuditLogDB(t)
+	start := time.Now()
+
+	for i := 0; i < 5; i++ {
+		e := entity.NewAuditEvent(entity.AuditLoginFailed, "user", "user-id")
+		e.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		assert.Nil(t, auditDB.Append(e))
+	}
+
+	other := entity.NewAuditEvent(entity.AuditProductDeleted, "product", "product-id")
+	other.ActorID = "admin-id"
+	assert.Nil(t, auditDB.Append(other))
+
+	events, total, err := auditDB.Find(database.AuditEventFilter{Action: entity.AuditLoginFailed}, 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Len(t, events, 2)
+	assert.True(t, events[0].CreatedAt.After(events[1].CreatedAt))
+
+	events, _, err = auditDB.Find(database.AuditEventFilter{Action: entity.AuditLoginFailed}, 3, 2)
+	assert.Nil(t, err)
+	assert.Len(t, events, 1)
+
+	from := start.Add(time.Minute)
+	to := start.Add(3 * time.Minute)
+	_, total, err = auditDB.Find(database.AuditEventFilter{ResourceType: "user", From: &from, To: &to}, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), total)
+
+	events, total, err = auditDB.Find(database.AuditEventFilter{ActorID: "admin-id"}, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, other.ID, events[0].ID)
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"time"
+
+	"app/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type EmailVerificationToken struct {
+	DB *gorm.DB
+}
+
+func NewEmailVerificationToken(db *gorm.DB) *EmailVerificationToken {
+	return &EmailVerificationToken{DB: db}
+}
+
+// Create stores the token and invalidates any verification token still pending
+// for the same user, so only the most recent email works.
+func (e *EmailVerificationToken) Create(token *entity.EmailVerificationToken) error {
+	err := e.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.EmailVerificationToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(token).Error
+	})
+
+	return translateError(e.DB, err)
+}
+
+func (e *EmailVerificationToken) FindByHash(hash string) (*entity.EmailVerificationToken, error) {
+	token := &entity.EmailVerificationToken{}
+	err := e.DB.Where("token_hash = ?", hash).First(token).Error
+	return token, translateError(e.DB, err)
+}
+
+// MarkUsed consumes the token. It fails with ErrNotFound when the token was
+// already used, which makes concurrent confirmations safe.
+func (e *EmailVerificationToken) MarkUsed(token *entity.EmailVerificationToken) error {
+	now := time.Now()
+
+	result := e.DB.Model(&entity.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return translateError(e.DB, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	token.UsedAt = &now
+	return nil
+}
@@ -0,0 +1,61 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeInMemoryEmailVerificationTokenDB(t *testing.T) *database.EmailVerificationToken {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.EmailVerificationToken{})
+
+	return database.NewEmailVerificationToken(db)
+}
+
+func TestCreateEmailVerificationToken(t *testing.T) {
+	tokenDB := makeInMemoryEmailVerificationTokenDB(t)
+
+	first, firstToken, _ := entity.NewEmailVerificationToken("user-id", time.Hour)
+	assert.Nil(t, tokenDB.Create(first))
+
+	found, err := tokenDB.FindByHash(entity.HashToken(firstToken))
+	assert.Nil(t, err)
+	assert.Equal(t, first.ID, found.ID)
+	assert.False(t, found.IsUsed())
+
+	second, secondToken, _ := entity.NewEmailVerificationToken("user-id", time.Hour)
+	assert.Nil(t, tokenDB.Create(second))
+
+	found, _ = tokenDB.FindByHash(entity.HashToken(firstToken))
+	assert.True(t, found.IsUsed())
+	found, _ = tokenDB.FindByHash(entity.HashToken(secondToken))
+	assert.False(t, found.IsUsed())
+}
+
+func TestMarkEmailVerificationTokenUsed(t *testing.T) {
+	tokenDB := makeInMemoryEmailVerificationTokenDB(t)
+
+	prt, token, _ := entity.NewEmailVerificationToken("user-id", time.Hour)
+	assert.Nil(t, tokenDB.Create(prt))
+
+	err := tokenDB.MarkUsed(prt)
+	assert.Nil(t, err)
+	assert.True(t, prt.IsUsed())
+
+	found, _ := tokenDB.FindByHash(entity.HashToken(token))
+	assert.True(t, found.IsUsed())
+
+	err = tokenDB.MarkUsed(found)
+	assert.NotNil(t, err)
+}
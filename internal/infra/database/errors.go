@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Every repository method fails with one of these kinds, wrapping the driver
+// error, or with an unclassified error. Callers check them with errors.Is.
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrConstraintViolation = errors.New("constraint violation")
+	ErrUnavailable         = errors.New("database unavailable")
+)
+
+var ErrEmailAlreadyExists = fmt.Errorf("email already exists: %w", ErrConflict)
+
+// ErrVersionConflict means a row changed since it was read, so a write
+// conditioned on the version read failed.
+var ErrVersionConflict = fmt.Errorf("version conflict: %w", ErrConflict)
+
+// SQLite primary result codes, see https://www.sqlite.org/rescode.html.
+const (
+	sqliteBusy       = 5
+	sqliteLocked     = 6
+	sqliteIOErr      = 10
+	sqliteFull       = 13
+	sqliteCantOpen   = 14
+	sqliteConstraint = 19
+)
+
+// translateError maps driver specific errors to the error kinds of this
+// package, even when the connection was opened without
+// gorm.Config.TranslateError.
+func translateError(db *gorm.DB, err error) error {
+	if err == nil || isTranslated(err) {
+		return err
+	}
+
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		if translated := translator.Translate(err); translated != err {
+			err = fmt.Errorf("%w: %v", translated, err)
+		}
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	case errors.Is(err, gorm.ErrForeignKeyViolated),
+		errors.Is(err, gorm.ErrCheckConstraintViolated),
+		sqliteCode(err) == sqliteConstraint:
+		return fmt.Errorf("%w: %w", ErrConstraintViolation, err)
+	case isUnavailable(err):
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	return err
+}
+
+func isTranslated(err error) bool {
+	return errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrConflict) ||
+		errors.Is(err, ErrConstraintViolation) ||
+		errors.Is(err, ErrUnavailable)
+}
+
+func isUnavailable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	switch sqliteCode(err) {
+	case sqliteBusy, sqliteLocked, sqliteIOErr, sqliteFull, sqliteCantOpen:
+		return true
+	}
+
+	return false
+}
+
+// sqliteCode reads the primary result code of a sqlite3.Error the way the
+// gorm sqlite dialector does, through its JSON form, to avoid depending on the
+// cgo driver here. It is 0 for any other error.
+func sqliteCode(err error) int {
+	for ; err != nil; err = errors.Unwrap(err) {
+		raw, marshalErr := json.Marshal(err)
+		if marshalErr != nil {
+			continue
+		}
+
+		var parsed struct {
+			Code int `json:"Code"`
+		}
+		if json.Unmarshal(raw, &parsed) == nil && parsed.Code != 0 {
+			return parsed.Code
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+	"app/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRepositoryErrors_NotFound(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	_, err := productDB.FindById("missing")
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	p, _ := entity.NewProduct("Product 1", money.MustParse("10", "USD"))
+	assert.ErrorIs(t, productDB.Update(p, "actor"), database.ErrNotFound)
+	assert.ErrorIs(t, productDB.Delete(p, "actor"), database.ErrNotFound)
+}
+
+func TestRepositoryErrors_Conflict(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	db.AutoMigrate(&entity.Product{}, &entity.ProductRevision{})
+
+	productDB := database.NewProduct(db)
+	p, _ := entity.NewProduct("Product 1", money.MustParse("10", "USD"))
+	require.NoError(t, productDB.Create(p, "actor"))
+
+	err = productDB.Create(p, "actor")
+	assert.ErrorIs(t, err, database.ErrConflict)
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+}
+
+func TestRepositoryErrors_ConstraintViolation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	err = db.Exec(`CREATE TABLE products (
+		id TEXT PRIMARY KEY,
+		name TEXT CHECK (name <> ''),
+		price_amount INTEGER,
+		price_currency TEXT,
+		owner_id TEXT,
+		version INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME,
+		deleted_at DATETIME
+	)`).Error
+	require.NoError(t, err)
+
+	productDB := database.NewProduct(db)
+	err = productDB.Create(&entity.Product{ID: "1", Price: money.MustParse("10", "USD")}, "actor")
+	assert.ErrorIs(t, err, database.ErrConstraintViolation)
+}
+
+func TestRepositoryErrors_Unavailable(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	db.AutoMigrate(&entity.Product{}, &entity.ProductRevision{})
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	productDB := database.NewProduct(db.WithContext(ctx))
+	_, err = productDB.FindAll(1, 10, "asc")
+	assert.ErrorIs(t, err, database.ErrUnavailable)
+}
@@ -1,16 +1,98 @@
 package database
 
-import "app/internal/entity"
+import (
+	"time"
+
+	"app/internal/entity"
+)
 
 type UserInterface interface {
 	Create(user *entity.User) error
 	FindByEmail(email string) (*entity.User, error)
+	FindByID(id string) (*entity.User, error)
+	FindAll(search string, page, limit int) ([]entity.User, int64, error)
+	Update(user *entity.User) error
+	Delete(id string) error
 }
 
 type ProductInterface interface {
-	Create(product *entity.Product) error
+	Create(product *entity.Product, actorID string) error
 	FindAll(page, limit int, sort string) ([]entity.Product, error)
+	FindAllByOwner(ownerID string, page, limit int, sort string) ([]entity.Product, error)
 	FindById(id string) (*entity.Product, error)
-	Update(product *entity.Product) error
+	Update(product *entity.Product, actorID string) error
+	Delete(product *entity.Product, actorID string) error
+	FindAllDeleted(page, limit int, sort string) ([]entity.Product, error)
+	FindAllDeletedByOwner(ownerID string, page, limit int, sort string) ([]entity.Product, error)
+	FindDeletedById(id string) (*entity.Product, error)
+	Restore(product *entity.Product, actorID string) error
+	Purge(id string) error
+	PurgeDeletedBefore(cutoff time.Time) (int64, error)
+	FindRevisions(productID string, page, limit int) ([]entity.ProductRevision, error)
+	FindRevision(productID string, number int) (*entity.ProductRevision, error)
+	FindLatestRevision(productID string) (*entity.ProductRevision, error)
+}
+
+type RefreshTokenInterface interface {
+	Create(token *entity.RefreshToken) error
+	FindByHash(hash string) (*entity.RefreshToken, error)
+	Rotate(current *entity.RefreshToken, next *entity.RefreshToken) error
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID string) error
+}
+
+type TokenRevocationInterface interface {
+	Revoke(token *entity.RevokedToken) error
+	RevokeAllForUser(userID string, before time.Time) error
+	IsRevoked(jti, userID string, issuedAt time.Time) (bool, error)
+	DeleteExpired() error
+}
+
+type PasswordResetTokenInterface interface {
+	Create(token *entity.PasswordResetToken) error
+	FindByHash(hash string) (*entity.PasswordResetToken, error)
+	Redeem(token *entity.PasswordResetToken, user *entity.User) error
+}
+
+type EmailVerificationTokenInterface interface {
+	Create(token *entity.EmailVerificationToken) error
+	FindByHash(hash string) (*entity.EmailVerificationToken, error)
+	MarkUsed(token *entity.EmailVerificationToken) error
+}
+
+type LoginThrottleInterface interface {
+	FindAccountLockout(email string) (*entity.AccountLockout, error)
+	UpdateAccountLockout(email string, update func(*entity.AccountLockout) bool) (*entity.AccountLockout, error)
+	DeleteAccountLockout(email string) error
+	RecordIPAttempt(ip string, at, since time.Time, limit int) (bool, error)
+	IPAttemptsSince(ip string, since time.Time) ([]time.Time, error)
+	DeleteIPAttemptsBefore(before time.Time) error
+}
+
+type RecoveryCodeInterface interface {
+	ReplaceForUser(userID string, codes []*entity.RecoveryCode) error
+	Use(userID, hash string) error
+	CountUnused(userID string) (int64, error)
+	DeleteForUser(userID string) error
+}
+
+type APIKeyInterface interface {
+	Create(key *entity.APIKey) error
+	FindByHash(hash string) (*entity.APIKey, error)
+	FindAllByUser(userID string) ([]entity.APIKey, error)
+	Revoke(id, userID string) error
+	TouchLastUsed(id string, at time.Time) error
+}
+
+type OAuthClientInterface interface {
+	Create(client *entity.OAuthClient) error
+	FindByID(id string) (*entity.OAuthClient, error)
+	FindAll() ([]entity.OAuthClient, error)
+	Update(client *entity.OAuthClient) error
 	Delete(id string) error
 }
+
+type AuditLogInterface interface {
+	Append(event *entity.AuditEvent) error
+	Find(filter AuditEventFilter, page, limit int) ([]entity.AuditEvent, int64, error)
+}
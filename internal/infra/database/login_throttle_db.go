@@ -0,0 +1,110 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"app/internal/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottle struct {
+	DB *gorm.DB
+}
+
+func NewLoginThrottle(db *gorm.DB) *LoginThrottle {
+	return &LoginThrottle{DB: db}
+}
+
+// FindAccountLockout returns an empty lockout when the email has no recorded
+// failures.
+func (l *LoginThrottle) FindAccountLockout(email string) (*entity.AccountLockout, error) {
+	lockout := &entity.AccountLockout{}
+	err := l.DB.First(lockout, "email = ?", entity.NormalizeEmail(email)).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.NewAccountLockout(email), nil
+	}
+
+	return lockout, translateError(l.DB, err)
+}
+
+// maxLockoutUpdates bounds how many times UpdateAccountLockout retries after
+// losing a race.
+const maxLockoutUpdates = 10
+
+// UpdateAccountLockout applies update to the lockout of the email as a compare
+// and swap on its version, reading it again and retrying when another request
+// changed it in the meantime. update returns false to leave it unchanged.
+func (l *LoginThrottle) UpdateAccountLockout(email string, update func(*entity.AccountLockout) bool) (*entity.AccountLockout, error) {
+	for i := 0; i < maxLockoutUpdates; i++ {
+		lockout, err := l.FindAccountLockout(email)
+		if err != nil {
+			return nil, err
+		}
+
+		version := lockout.Version
+		if !update(lockout) {
+			return lockout, nil
+		}
+		lockout.Version = version + 1
+
+		var result *gorm.DB
+		if version == 0 {
+			result = l.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(lockout)
+		} else {
+			result = l.DB.Model(lockout).Where("version = ?", version).Select("*").Updates(lockout)
+		}
+		if result.Error != nil {
+			return nil, translateError(l.DB, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			return lockout, nil
+		}
+	}
+
+	return nil, ErrVersionConflict
+}
+
+func (l *LoginThrottle) DeleteAccountLockout(email string) error {
+	err := l.DB.Delete(&entity.AccountLockout{}, "email = ?", entity.NormalizeEmail(email)).Error
+	return translateError(l.DB, err)
+}
+
+// RecordIPAttempt records the attempt unless the IP already made limit
+// attempts after since. The count and the insert are a single statement, so
+// concurrent requests cannot all slip under the limit.
+func (l *LoginThrottle) RecordIPAttempt(ip string, at, since time.Time, limit int) (bool, error) {
+	result := l.DB.Exec(
+		"INSERT INTO login_attempts (ip, created_at) SELECT ?, ? FROM "+
+			"(SELECT COUNT(*) AS attempts FROM login_attempts WHERE ip = ? AND created_at > ?) AS recent "+
+			"WHERE recent.attempts < ?",
+		ip, at, ip, since, limit,
+	)
+	if result.Error != nil {
+		return false, translateError(l.DB, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (l *LoginThrottle) IPAttemptsSince(ip string, since time.Time) ([]time.Time, error) {
+	var attempts []entity.LoginAttempt
+	err := l.DB.Where("ip = ? AND created_at > ?", ip, since).Order("created_at asc").Find(&attempts).Error
+	if err != nil {
+		return nil, translateError(l.DB, err)
+	}
+
+	times := make([]time.Time, len(attempts))
+	for i, attempt := range attempts {
+		times[i] = attempt.CreatedAt
+	}
+
+	return times, nil
+}
+
+func (l *LoginThrottle) DeleteIPAttemptsBefore(before time.Time) error {
+	err := l.DB.Where("created_at <= ?", before).Delete(&entity.LoginAttempt{}).Error
+	return translateError(l.DB, err)
+}
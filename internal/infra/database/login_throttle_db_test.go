@@ -0,0 +1,193 @@
+package database_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeLoginThrottleStores(t *testing.T) map[string]database.LoginThrottleInterface {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.AccountLockout{}, &entity.LoginAttempt{})
+
+	// A second connection would open another, empty, in-memory database.
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	return map[string]database.LoginThrottleInterface{
+		"gorm":      database.NewLoginThrottle(db),
+		"in-memory": database.NewInMemoryLoginThrottle(),
+	}
+}
+
+func TestAccountLockoutStore(t *testing.T) {
+	for name, store := range makeLoginThrottleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			lockout, err := store.FindAccountLockout("John@Doe.com")
+			assert.Nil(t, err)
+			assert.Equal(t, "john@doe.com", lockout.Email)
+			assert.Equal(t, 0, lockout.Failures)
+
+			lockout, err = store.UpdateAccountLockout("john@doe.com", func(lockout *entity.AccountLockout) bool {
+				lockout.RegisterFailure(entity.LoginThrottlePolicy{BaseDelay: time.Minute}, time.Now())
+				return true
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, lockout.Version)
+
+			found, err := store.FindAccountLockout("john@doe.com")
+			assert.Nil(t, err)
+			assert.Equal(t, 1, found.Failures)
+			assert.True(t, found.RetryAfter(time.Now()) > 0)
+
+			err = store.DeleteAccountLockout("JOHN@doe.com")
+			assert.Nil(t, err)
+
+			found, err = store.FindAccountLockout("john@doe.com")
+			assert.Nil(t, err)
+			assert.Equal(t, 0, found.Failures)
+		})
+	}
+}
+
+func TestAccountLockoutConcurrentUpdates(t *testing.T) {
+	for name, store := range makeLoginThrottleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := store.UpdateAccountLockout("john@doe.com", func(lockout *entity.AccountLockout) bool {
+						lockout.Failures++
+						return true
+					})
+					assert.Nil(t, err)
+				}()
+			}
+			wg.Wait()
+
+			found, err := store.FindAccountLockout("john@doe.com")
+			assert.Nil(t, err)
+			assert.Equal(t, 5, found.Failures)
+		})
+	}
+}
+
+func TestAccountLockoutUpdateRetriesAfterLosingRace(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.AccountLockout{})
+	store := database.NewLoginThrottle(db)
+
+	increment := func(lockout *entity.AccountLockout) bool {
+		lockout.Failures++
+		return true
+	}
+
+	calls := 0
+	lockout, err := store.UpdateAccountLockout("john@doe.com", func(lockout *entity.AccountLockout) bool {
+		calls++
+		if calls == 1 {
+			_, err := store.UpdateAccountLockout("john@doe.com", increment)
+			assert.Nil(t, err)
+		}
+		return increment(lockout)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, lockout.Failures)
+	assert.Equal(t, 2, lockout.Version)
+}
+
+func TestIPAttemptStore(t *testing.T) {
+	for name, store := range makeLoginThrottleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+
+			for _, at := range []time.Time{now.Add(-2 * time.Minute), now.Add(-30 * time.Second), now} {
+				recorded, err := store.RecordIPAttempt("10.0.0.1", at, now.Add(-time.Hour), 10)
+				assert.Nil(t, err)
+				assert.True(t, recorded)
+			}
+			recorded, err := store.RecordIPAttempt("10.0.0.2", now, now.Add(-time.Hour), 10)
+			assert.Nil(t, err)
+			assert.True(t, recorded)
+
+			attempts, err := store.IPAttemptsSince("10.0.0.1", now.Add(-time.Minute))
+			assert.Nil(t, err)
+			assert.Len(t, attempts, 2)
+			assert.True(t, attempts[0].Before(attempts[1]))
+
+			err = store.DeleteIPAttemptsBefore(now.Add(-time.Second))
+			assert.Nil(t, err)
+
+			attempts, err = store.IPAttemptsSince("10.0.0.1", now.Add(-time.Hour))
+			assert.Nil(t, err)
+			assert.Len(t, attempts, 1)
+		})
+	}
+}
+
+func TestIPAttemptStoreRefusesAttemptsOverTheLimit(t *testing.T) {
+	for name, store := range makeLoginThrottleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+
+			recorded, err := store.RecordIPAttempt("10.0.0.1", now.Add(-2*time.Minute), now.Add(-time.Hour), 2)
+			assert.Nil(t, err)
+			assert.True(t, recorded)
+			recorded, err = store.RecordIPAttempt("10.0.0.1", now, now.Add(-time.Hour), 2)
+			assert.Nil(t, err)
+			assert.True(t, recorded)
+
+			recorded, err = store.RecordIPAttempt("10.0.0.1", now, now.Add(-time.Hour), 2)
+			assert.Nil(t, err)
+			assert.False(t, recorded)
+
+			recorded, err = store.RecordIPAttempt("10.0.0.1", now, now.Add(-time.Minute), 2)
+			assert.Nil(t, err)
+			assert.True(t, recorded)
+
+			attempts, err := store.IPAttemptsSince("10.0.0.1", now.Add(-time.Hour))
+			assert.Nil(t, err)
+			assert.Len(t, attempts, 3)
+		})
+	}
+}
+
+func TestIPAttemptStoreConcurrentAttempts(t *testing.T) {
+	for name, store := range makeLoginThrottleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := store.RecordIPAttempt("10.0.0.1", now, now.Add(-time.Minute), 3)
+					assert.Nil(t, err)
+				}()
+			}
+			wg.Wait()
+
+			attempts, err := store.IPAttemptsSince("10.0.0.1", now.Add(-time.Minute))
+			assert.Nil(t, err)
+			assert.Len(t, attempts, 3)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"sync"
+	"time"
+
+	"app/internal/entity"
+)
+
+// InMemoryLoginThrottle keeps login counters in process memory. It is meant
+// for tests and single instance deployments, counters are lost on restart.
+type InMemoryLoginThrottle struct {
+	mu       sync.Mutex
+	lockouts map[string]entity.AccountLockout
+	attempts map[string][]time.Time
+}
+
+func NewInMemoryLoginThrottle() *InMemoryLoginThrottle {
+	return &InMemoryLoginThrottle{
+		lockouts: map[string]entity.AccountLockout{},
+		attempts: map[string][]time.Time{},
+	}
+}
+
+func (l *InMemoryLoginThrottle) FindAccountLockout(email string) (*entity.AccountLockout, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lockout, ok := l.lockouts[entity.NormalizeEmail(email)]; ok {
+		return &lockout, nil
+	}
+
+	return entity.NewAccountLockout(email), nil
+}
+
+// UpdateAccountLockout applies update to the lockout of the email while
+// holding the lock, so concurrent updates never overwrite each other.
+func (l *InMemoryLoginThrottle) UpdateAccountLockout(email string, update func(*entity.AccountLockout) bool) (*entity.AccountLockout, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lockout := entity.NewAccountLockout(email)
+	if stored, ok := l.lockouts[lockout.Email]; ok {
+		*lockout = stored
+	}
+
+	if !update(lockout) {
+		return lockout, nil
+	}
+
+	lockout.Version++
+	l.lockouts[lockout.Email] = *lockout
+	return lockout, nil
+}
+
+func (l *InMemoryLoginThrottle) DeleteAccountLockout(email string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.lockouts, entity.NormalizeEmail(email))
+	return nil
+}
+
+func (l *InMemoryLoginThrottle) RecordIPAttempt(ip string, at, since time.Time, limit int) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := 0
+	for _, attempt := range l.attempts[ip] {
+		if attempt.After(since) {
+			count++
+		}
+	}
+
+	if count >= limit {
+		return false, nil
+	}
+
+	l.attempts[ip] = append(l.attempts[ip], at)
+	return true, nil
+}
+
+func (l *InMemoryLoginThrottle) IPAttemptsSince(ip string, since time.Time) ([]time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var times []time.Time
+	for _, at := range l.attempts[ip] {
+		if at.After(since) {
+			times = append(times, at)
+		}
+	}
+
+	return times, nil
+}
+
+func (l *InMemoryLoginThrottle) DeleteIPAttemptsBefore(before time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ip, attempts := range l.attempts {
+		kept := attempts[:0]
+		for _, at := range attempts {
+			if at.After(before) {
+				kept = append(kept, at)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(l.attempts, ip)
+		} else {
+			l.attempts[ip] = kept
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"app/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type OAuthClient struct {
+	DB *gorm.DB
+}
+
+func NewOAuthClient(db *gorm.DB) *OAuthClient {
+	return &OAuthClient{DB: db}
+}
+
+func (o *OAuthClient) Create(client *entity.OAuthClient) error {
+	return translateError(o.DB, o.DB.Create(client).Error)
+}
+
+func (o *OAuthClient) FindByID(id string) (*entity.OAuthClient, error) {
+	client := &entity.OAuthClient{}
+	err := o.DB.Where("id = ?", id).First(client).Error
+	return client, translateError(o.DB, err)
+}
+
+func (o *OAuthClient) FindAll() ([]entity.OAuthClient, error) {
+	var clients []entity.OAuthClient
+	err := o.DB.Order("created_at asc").Find(&clients).Error
+	return clients, translateError(o.DB, err)
+}
+
+func (o *OAuthClient) Update(client *entity.OAuthClient) error {
+	return translateError(o.DB, o.DB.Save(client).Error)
+}
+
+// Delete removes the client. It fails with ErrNotFound when there is no such
+// client.
+func (o *OAuthClient) Delete(id string) error {
+	result := o.DB.Where("id = ?", id).Delete(&entity.OAuthClient{})
+	if result.Error != nil {
+		return translateError(o.DB, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package database_test
+
+import (
+	"testing"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeInMemoryOAuthClientDB(t *testing.T) *database.OAuthClient {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.OAuthClient{})
+
+	return database.NewOAuthClient(db)
+}
+
+func TestCreateOAuthClient(t *testing.T) {
+	clientDB := makeInMemoryOAuthClientDB(t)
+
+	c, secret, _ := entity.NewOAuthClient("Partner", []entity.Scope{entity.ScopeProductsRead}, "admin-id")
+	assert.Nil(t, clientDB.Create(c))
+
+	found, err := clientDB.FindByID(c.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, c.Name, found.Name)
+	assert.True(t, found.ValidateSecret(secret))
+
+	clients, err := clientDB.FindAll()
+	assert.Nil(t, err)
+	assert.Len(t, clients, 1)
+}
+
+func TestUpdateOAuthClient(t *testing.T) {
+	clientDB := makeInMemoryOAuthClientDB(t)
+
+	c, _, _ := entity.NewOAuthClient("Partner", []entity.Scope{entity.ScopeProductsRead}, "admin-id")
+	assert.Nil(t, clientDB.Create(c))
+
+	secret, _ := c.RotateSecret()
+	assert.Nil(t, clientDB.Update(c))
+
+	found, err := clientDB.FindByID(c.ID)
+	assert.Nil(t, err)
+	assert.True(t, found.ValidateSecret(secret))
+}
+
+func TestDeleteOAuthClient(t *testing.T) {
+	clientDB := makeInMemoryOAuthClientDB(t)
+
+	c, _, _ := entity.NewOAuthClient("Partner", []entity.Scope{entity.ScopeProductsRead}, "admin-id")
+	assert.Nil(t, clientDB.Create(c))
+
+	assert.Nil(t, clientDB.Delete(c.ID))
+	assert.ErrorIs(t, clientDB.Delete(c.ID), database.ErrNotFound)
+
+	_, err := clientDB.FindByID(c.ID)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"time"
+
+	"app/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetToken struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetToken(db *gorm.DB) *PasswordResetToken {
+	return &PasswordResetToken{DB: db}
+}
+
+// Create stores the token and invalidates any reset token still pending for
+// the same user, so only the most recent email works.
+func (p *PasswordResetToken) Create(token *entity.PasswordResetToken) error {
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(token).Error
+	})
+
+	return translateError(p.DB, err)
+}
+
+func (p *PasswordResetToken) FindByHash(hash string) (*entity.PasswordResetToken, error) {
+	token := &entity.PasswordResetToken{}
+	err := p.DB.Where("token_hash = ?", hash).First(token).Error
+	return token, translateError(p.DB, err)
+}
+
+// Redeem consumes the token and stores the new password of its user in one
+// transaction, so the token is never spent without the password changing. It
+// fails with ErrNotFound when the token was already used, which makes
+// concurrent confirmations safe, or the user no longer exists.
+func (p *PasswordResetToken) Redeem(token *entity.PasswordResetToken, user *entity.User) error {
+	now := time.Now()
+
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		result = tx.Model(user).Select("password", "password_reset_required").Updates(user)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return translateError(p.DB, err)
+	}
+
+	token.UsedAt = &now
+	return nil
+}
@@ -0,0 +1,83 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeInMemoryPasswordResetTokenDB(t *testing.T) *database.PasswordResetToken {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.PasswordResetToken{}, &entity.User{})
+
+	return database.NewPasswordResetToken(db)
+}
+
+func TestCreatePasswordResetToken(t *testing.T) {
+	tokenDB := makeInMemoryPasswordResetTokenDB(t)
+
+	first, firstToken, _ := entity.NewPasswordResetToken("user-id", time.Hour)
+	assert.Nil(t, tokenDB.Create(first))
+
+	found, err := tokenDB.FindByHash(entity.HashToken(firstToken))
+	assert.Nil(t, err)
+	assert.Equal(t, first.ID, found.ID)
+	assert.False(t, found.IsUsed())
+
+	second, secondToken, _ := entity.NewPasswordResetToken("user-id", time.Hour)
+	assert.Nil(t, tokenDB.Create(second))
+
+	found, _ = tokenDB.FindByHash(entity.HashToken(firstToken))
+	assert.True(t, found.IsUsed())
+	found, _ = tokenDB.FindByHash(entity.HashToken(secondToken))
+	assert.False(t, found.IsUsed())
+}
+
+func TestRedeemPasswordResetToken(t *testing.T) {
+	tokenDB := makeInMemoryPasswordResetTokenDB(t)
+	userDB := database.NewUser(tokenDB.DB)
+
+	u, _ := entity.NewUser("John Doe", "john@doe.com", "Secret-123")
+	assert.Nil(t, userDB.Create(u))
+
+	prt, token, _ := entity.NewPasswordResetToken(u.ID, time.Hour)
+	assert.Nil(t, tokenDB.Create(prt))
+
+	assert.Nil(t, u.ChangePassword("Other-Secret-456"))
+	err := tokenDB.Redeem(prt, u)
+	assert.Nil(t, err)
+	assert.True(t, prt.IsUsed())
+
+	found, _ := tokenDB.FindByHash(entity.HashToken(token))
+	assert.True(t, found.IsUsed())
+
+	stored, _ := userDB.FindByID(u.ID)
+	assert.True(t, stored.ValidatePassword("Other-Secret-456"))
+
+	err = tokenDB.Redeem(found, u)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestRedeemPasswordResetTokenOfDeletedUser(t *testing.T) {
+	tokenDB := makeInMemoryPasswordResetTokenDB(t)
+
+	u, _ := entity.NewUser("John Doe", "john@doe.com", "Secret-123")
+	prt, token, _ := entity.NewPasswordResetToken(u.ID, time.Hour)
+	assert.Nil(t, tokenDB.Create(prt))
+
+	err := tokenDB.Redeem(prt, u)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	found, _ := tokenDB.FindByHash(entity.HashToken(token))
+	assert.False(t, found.IsUsed())
+}
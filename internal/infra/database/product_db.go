@@ -1,7 +1,14 @@
 package database
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"app/internal/entity"
+	"app/pkg/money"
 
 	"gorm.io/gorm"
 )
@@ -14,47 +21,354 @@ func NewProduct(db *gorm.DB) *Product {
 	return &Product{DB: db}
 }
 
-func (p *Product) Create(product *entity.Product) error {
-	return p.DB.Create(product).Error
+// Create stores the product along with its first revision.
+func (p *Product) Create(product *entity.Product, actorID string) error {
+	return p.transaction(func(tx *Product) error {
+		if err := tx.DB.Create(product).Error; err != nil {
+			return translateError(tx.DB, err)
+		}
+		return tx.createRevision(entity.RevisionCreated, product, actorID)
+	})
 }
 
 func (p *Product) FindAll(page, limit int, sort string) ([]entity.Product, error) {
-	var products []entity.Product
-	var err error
+	return p.findAll(p.DB, page, limit, sort)
+}
+
+func (p *Product) FindAllByOwner(ownerID string, page, limit int, sort string) ([]entity.Product, error) {
+	return p.findAll(p.DB.Where("owner_id = ?", ownerID), page, limit, sort)
+}
 
+// FindAllDeleted returns the products in the trash, ordered by when they were
+// deleted, most recent first unless sort is "asc".
+func (p *Product) FindAllDeleted(page, limit int, sort string) ([]entity.Product, error) {
+	return p.findAllDeleted(p.trash(), page, limit, sort)
+}
+
+func (p *Product) FindAllDeletedByOwner(ownerID string, page, limit int, sort string) ([]entity.Product, error) {
+	return p.findAllDeleted(p.trash().Where("owner_id = ?", ownerID), page, limit, sort)
+}
+
+func (p *Product) findAll(query *gorm.DB, page, limit int, sort string) ([]entity.Product, error) {
 	if sort != "" && sort != "asc" && sort != "desc" {
 		sort = "asc"
 	}
 
+	return p.find(query, page, limit, "created_at "+sort)
+}
+
+func (p *Product) findAllDeleted(query *gorm.DB, page, limit int, sort string) ([]entity.Product, error) {
+	if sort != "asc" {
+		sort = "desc"
+	}
+
+	return p.find(query, page, limit, "deleted_at "+sort)
+}
+
+func (p *Product) find(query *gorm.DB, page, limit int, order string) ([]entity.Product, error) {
+	var products []entity.Product
+	var err error
+
 	if limit != 0 && page != 0 {
-		err = p.DB.Offset((page - 1) * limit).Limit(limit).Order("created_at " + sort).Find(&products).Error
+		err = query.Offset((page - 1) * limit).Limit(limit).Order(order).Find(&products).Error
 	} else {
-		err = p.DB.Order("created_at " + sort).Find(&products).Error
+		err = query.Order(order).Find(&products).Error
 	}
 
-	return products, err
+	return products, translateError(p.DB, err)
+}
+
+// trash scopes a query to the soft deleted products only.
+func (p *Product) trash() *gorm.DB {
+	return p.DB.Unscoped().Where("deleted_at IS NOT NULL")
+}
+
+// scope returns a query on the products in the trash when inTrash is set, and
+// on the others otherwise.
+func (p *Product) scope(inTrash bool) *gorm.DB {
+	if inTrash {
+		return p.trash()
+	}
+	return p.DB
 }
 
 func (p *Product) FindById(id string) (*entity.Product, error) {
 	product := &entity.Product{}
 	err := p.DB.First(product, "id = ?", id).Error
-	return product, err
+	return product, translateError(p.DB, err)
 }
 
-func (p *Product) Update(product *entity.Product) error {
-	_, err := p.FindById(product.ID)
+// FindDeletedById finds a product in the trash.
+func (p *Product) FindDeletedById(id string) (*entity.Product, error) {
+	product := &entity.Product{}
+	err := p.trash().First(product, "id = ?", id).Error
+	return product, translateError(p.DB, err)
+}
+
+// Update saves the product if it is still at product.Version, as a compare and
+// swap, and then increments product.Version. It fails with ErrVersionConflict
+// when the product was changed in the meantime.
+func (p *Product) Update(product *entity.Product, actorID string) error {
+	next := *product
+	next.Version++
+
+	err := p.transaction(func(tx *Product) error {
+		result := tx.DB.Model(&next).Where("version = ?", product.Version).Select("*").Updates(&next)
+		if err := tx.casResult(false, product.ID, result); err != nil {
+			return err
+		}
+		return tx.createRevision(entity.RevisionUpdated, &next, actorID)
+	})
 	if err != nil {
 		return err
 	}
 
-	return p.DB.Save(product).Error
+	product.Version = next.Version
+	return nil
+}
+
+// Delete moves the product to the trash if it is still at product.Version,
+// failing with ErrVersionConflict otherwise. Like any change it increments
+// the version.
+func (p *Product) Delete(product *entity.Product, actorID string) error {
+	deletedAt := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return p.setDeletedAt(false, product, deletedAt, entity.RevisionDeleted, actorID)
 }
 
-func (p *Product) Delete(id string) error {
-	product, err := p.FindById(id)
+// Restore takes the product out of the trash if it is still at
+// product.Version, failing with ErrVersionConflict otherwise.
+func (p *Product) Restore(product *entity.Product, actorID string) error {
+	return p.setDeletedAt(true, product, gorm.DeletedAt{}, entity.RevisionRestored, actorID)
+}
+
+// setDeletedAt moves the product in or out of the trash, depending on whether
+// it is expected to be there, and records the change as a revision.
+func (p *Product) setDeletedAt(inTrash bool, product *entity.Product, deletedAt gorm.DeletedAt, action entity.RevisionAction, actorID string) error {
+	next := *product
+	next.Version++
+	next.DeletedAt = deletedAt
+
+	err := p.transaction(func(tx *Product) error {
+		result := tx.scope(inTrash).Model(&entity.Product{}).
+			Where("id = ? AND version = ?", product.ID, product.Version).
+			Updates(map[string]interface{}{"deleted_at": deletedAt, "version": next.Version})
+		if err := tx.casResult(inTrash, product.ID, result); err != nil {
+			return err
+		}
+		return tx.createRevision(action, &next, actorID)
+	})
 	if err != nil {
 		return err
 	}
 
-	return p.DB.Delete(product).Error
+	*product = next
+	return nil
+}
+
+// FindRevisions returns the revisions of a product, oldest first. They
+// outlive the product, even once purged.
+func (p *Product) FindRevisions(productID string, page, limit int) ([]entity.ProductRevision, error) {
+	var revisions []entity.ProductRevision
+
+	query := p.DB.Where("product_id = ?", productID).Order("number asc")
+	if limit != 0 && page != 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	err := query.Find(&revisions).Error
+	return revisions, translateError(p.DB, err)
+}
+
+func (p *Product) FindRevision(productID string, number int) (*entity.ProductRevision, error) {
+	revision := &entity.ProductRevision{}
+	err := p.DB.First(revision, "product_id = ? AND number = ?", productID, number).Error
+	return revision, translateError(p.DB, err)
+}
+
+func (p *Product) FindLatestRevision(productID string) (*entity.ProductRevision, error) {
+	revision := &entity.ProductRevision{}
+	err := p.DB.Where("product_id = ?", productID).Order("number desc").Take(revision).Error
+	return revision, translateError(p.DB, err)
+}
+
+func (p *Product) createRevision(action entity.RevisionAction, product *entity.Product, actorID string) error {
+	revision := entity.NewProductRevision(action, product, actorID)
+	return translateError(p.DB, p.DB.Create(revision).Error)
+}
+
+// transaction runs fn with a repository bound to a single transaction, which
+// is rolled back when fn fails.
+func (p *Product) transaction(fn func(tx *Product) error) error {
+	err := p.DB.Transaction(func(db *gorm.DB) error {
+		return fn(&Product{DB: db})
+	})
+	return translateError(p.DB, err)
+}
+
+// Purge permanently deletes a product from the trash. Products not in the
+// trash are not found.
+func (p *Product) Purge(id string) error {
+	result := p.trash().Delete(&entity.Product{}, "id = ?", id)
+	if result.Error != nil {
+		return translateError(p.DB, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeletedBefore permanently deletes the products trashed before cutoff
+// and returns how many there were.
+func (p *Product) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	result := p.trash().Where("deleted_at < ?", cutoff).Delete(&entity.Product{})
+	return result.RowsAffected, translateError(p.DB, result.Error)
+}
+
+// casResult tells a missing product from one at another version when a
+// conditional write matched no row. The product is looked for in the trash
+// when inTrash is set.
+func (p *Product) casResult(inTrash bool, id string, result *gorm.DB) error {
+	if result.Error != nil {
+		return translateError(p.DB, result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	exists := p.scope(inTrash).Select("id").Where("id = ?", id).Take(&entity.Product{}).Error
+	if exists != nil {
+		return translateError(p.DB, exists)
+	}
+	return ErrVersionConflict
+}
+
+// MigrateProductPrices converts the prices stored as floats, before they were
+// money, to amounts of currency rounded to its minor unit, stored in the
+// price_amount and price_currency columns. Revision snapshots holding float
+// prices are converted too. It runs before the products table is migrated and
+// does nothing once prices are money.
+func MigrateProductPrices(db *gorm.DB, currency string) error {
+	if !db.Migrator().HasTable(&entity.Product{}) {
+		return nil
+	}
+
+	columns, err := db.Migrator().ColumnTypes(&entity.Product{})
+	if err != nil {
+		return err
+	}
+	if !hasFloatColumn(columns, "price") {
+		return nil
+	}
+
+	units, err := money.MinorUnits(currency)
+	if err != nil {
+		return err
+	}
+	toMoney := func(price float64) (money.Money, error) {
+		return money.Parse(strconv.FormatFloat(price, 'f', units, 64), currency)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().RenameColumn(&entity.Product{}, "price", "float_price"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&entity.Product{}, "PriceAmount"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&entity.Product{}, "PriceCurrency"); err != nil {
+			return err
+		}
+
+		var rows []struct {
+			ID         string
+			FloatPrice float64
+		}
+		if err := tx.Table("products").Select("id", "float_price").Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			price, err := toMoney(row.FloatPrice)
+			if err != nil {
+				return fmt.Errorf("product %s: %w", row.ID, err)
+			}
+			err = tx.Table("products").Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"price_amount":   price.Amount(),
+				"price_currency": price.Currency(),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Migrator().DropColumn(&entity.Product{}, "float_price"); err != nil {
+			return err
+		}
+
+		return migrateRevisionPrices(tx, toMoney)
+	})
+}
+
+func hasFloatColumn(columns []gorm.ColumnType, name string) bool {
+	for _, column := range columns {
+		if column.Name() != name {
+			continue
+		}
+		switch strings.ToUpper(column.DatabaseTypeName()) {
+		case "REAL", "FLOAT", "DOUBLE", "DOUBLE PRECISION", "NUMERIC", "DECIMAL":
+			return true
+		}
+	}
+	return false
+}
+
+// migrateRevisionPrices rewrites the float prices of revision snapshots,
+// leaving everything else in them untouched.
+func migrateRevisionPrices(tx *gorm.DB, toMoney func(float64) (money.Money, error)) error {
+	if !tx.Migrator().HasTable(&entity.ProductRevision{}) {
+		return nil
+	}
+
+	var rows []struct {
+		ProductID string
+		Number    int
+		Snapshot  string
+	}
+	if err := tx.Table("product_revisions").Select("product_id", "number", "snapshot").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		var snapshot map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(row.Snapshot), &snapshot); err != nil {
+			return fmt.Errorf("revision %d of product %s: %w", row.Number, row.ProductID, err)
+		}
+
+		var price float64
+		if json.Unmarshal(snapshot["price"], &price) != nil {
+			continue
+		}
+
+		converted, err := toMoney(price)
+		if err != nil {
+			return fmt.Errorf("revision %d of product %s: %w", row.Number, row.ProductID, err)
+		}
+		if snapshot["price"], err = json.Marshal(converted); err != nil {
+			return err
+		}
+
+		raw, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		err = tx.Table("product_revisions").
+			Where("product_id = ? AND number = ?", row.ProductID, row.Number).
+			Update("snapshot", string(raw)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
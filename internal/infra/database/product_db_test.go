@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	"app/internal/entity"
 	"app/internal/infra/database"
+	"app/pkg/money"
 
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -19,7 +21,7 @@ func makeInMemoryProductDB(t *testing.T) *database.Product {
 		t.Error(err)
 	}
 
-	db.AutoMigrate(&entity.Product{})
+	db.AutoMigrate(&entity.Product{}, &entity.ProductRevision{})
 
 	return database.NewProduct(db)
 }
@@ -27,14 +29,14 @@ func makeInMemoryProductDB(t *testing.T) *database.Product {
 func TestNewProduct(t *testing.T) {
 	productDB := makeInMemoryProductDB(t)
 
-	p, err := entity.NewProduct("Product 1", 10.5)
+	p, err := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
 	assert.Nil(t, err)
-	err = productDB.Create(p)
+	err = productDB.Create(p, "actor")
 	assert.Nil(t, err)
 	assert.NotNil(t, p.ID)
 	assert.NotEmpty(t, p.ID)
 	assert.Equal(t, "Product 1", p.Name)
-	assert.Equal(t, 10.5, p.Price)
+	assert.Equal(t, money.MustParse("10.5", "USD"), p.Price)
 	assert.NotZero(t, p.CreatedAt)
 }
 
@@ -42,9 +44,9 @@ func TestFindAllProducts(t *testing.T) {
 	productDB := makeInMemoryProductDB(t)
 
 	for i := 1; i < 24; i++ {
-		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), rand.Float64()*1000)
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), money.MustParse(fmt.Sprintf("%d.99", rand.Intn(1000)), "USD"))
 		assert.NoError(t, err)
-		productDB.Create(product)
+		productDB.Create(product, "actor")
 	}
 
 	products, err := productDB.FindAll(1, 10, "asc")
@@ -60,12 +62,42 @@ func TestFindAllProducts(t *testing.T) {
 	assert.Equal(t, "Product 20", products[9].Name)
 }
 
+func TestFindAllProductsByOwner(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	for i := 1; i < 6; i++ {
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), money.MustParse(fmt.Sprintf("%d.99", rand.Intn(1000)), "USD"))
+		assert.NoError(t, err)
+		product.OwnerID = "owner-a"
+		if i%2 == 0 {
+			product.OwnerID = "owner-b"
+		}
+		productDB.Create(product, "actor")
+	}
+
+	products, err := productDB.FindAllByOwner("owner-a", 1, 10, "asc")
+	assert.NoError(t, err)
+	assert.Len(t, products, 3)
+	for _, product := range products {
+		assert.Equal(t, "owner-a", product.OwnerID)
+	}
+
+	products, err = productDB.FindAllByOwner("owner-b", 1, 1, "asc")
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Product 2", products[0].Name)
+
+	products, err = productDB.FindAllByOwner("owner-c", 1, 10, "asc")
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+}
+
 func TestFindProductById(t *testing.T) {
 	productDB := makeInMemoryProductDB(t)
 
-	p, err := entity.NewProduct("Product 1", 10.5)
+	p, err := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
 	assert.Nil(t, err)
-	err = productDB.Create(p)
+	err = productDB.Create(p, "actor")
 	assert.Nil(t, err)
 
 	pFound, err := productDB.FindById(p.ID)
@@ -75,39 +107,284 @@ func TestFindProductById(t *testing.T) {
 	assert.Equal(t, p.Price, pFound.Price)
 }
 
+func TestProductPricesAreStoredInMinorUnits(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	for _, price := range []string{"10.5", "2.25", "197.98"} {
+		p, err := entity.NewProduct("Product "+price, money.MustParse(price, "USD"))
+		assert.Nil(t, err)
+		assert.Nil(t, productDB.Create(p, "actor"))
+	}
+
+	var total int64
+	err := productDB.DB.Model(&entity.Product{}).Select("SUM(price_amount)").Scan(&total).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(21073), total)
+
+	var products []entity.Product
+	err = productDB.DB.Order("price_amount desc").Find(&products).Error
+	assert.Nil(t, err)
+	assert.Equal(t, money.MustParse("197.98", "USD"), products[0].Price)
+	assert.Equal(t, money.MustParse("2.25", "USD"), products[2].Price)
+}
+
 func TestUpdateProduct(t *testing.T) {
 	productDB := makeInMemoryProductDB(t)
 
-	p, err := entity.NewProduct("Product 1", 10.5)
+	p, err := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
 	assert.Nil(t, err)
-	err = productDB.Create(p)
+	err = productDB.Create(p, "actor")
 	assert.Nil(t, err)
 
 	p.Name = "Product 2"
-	p.Price = 20.5
-	err = productDB.Update(p)
+	p.Price = money.MustParse("20.5", "USD")
+	err = productDB.Update(p, "actor")
 	assert.Nil(t, err)
+	assert.Equal(t, 2, p.Version)
 
 	pFound, err := productDB.FindById(p.ID)
 	assert.Nil(t, err)
 	assert.Equal(t, p.ID, pFound.ID)
 	assert.Equal(t, p.Name, pFound.Name)
 	assert.Equal(t, p.Price, pFound.Price)
+	assert.Equal(t, 2, pFound.Version)
+	assert.WithinDuration(t, p.CreatedAt, pFound.CreatedAt, 0)
+}
+
+func TestUpdateProductVersionConflict(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	p, _ := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
+	assert.Nil(t, productDB.Create(p, "actor"))
+
+	stale := *p
+	p.Name = "Product 2"
+	assert.Nil(t, productDB.Update(p, "actor"))
+
+	stale.Name = "Product 3"
+	err := productDB.Update(&stale, "actor")
+	assert.ErrorIs(t, err, database.ErrVersionConflict)
+	assert.ErrorIs(t, err, database.ErrConflict)
+	assert.Equal(t, 1, stale.Version)
+
+	pFound, _ := productDB.FindById(p.ID)
+	assert.Equal(t, "Product 2", pFound.Name)
+
+	assert.ErrorIs(t, productDB.Delete(&stale, "actor"), database.ErrVersionConflict)
 }
 
 func TestDeleteProduct(t *testing.T) {
 	productDB := makeInMemoryProductDB(t)
 
-	p, err := entity.NewProduct("Product 1", 10.5)
+	p, err := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
 	assert.Nil(t, err)
-	err = productDB.Create(p)
+	err = productDB.Create(p, "actor")
 	assert.Nil(t, err)
 
-	err = productDB.Delete(p.ID)
+	err = productDB.Delete(p, "actor")
 	assert.Nil(t, err)
 
 	pFound, err := productDB.FindById(p.ID)
 
-	assert.NotNil(t, err)
+	assert.ErrorIs(t, err, database.ErrNotFound)
 	assert.Equal(t, entity.Product{}, *pFound)
+
+	products, err := productDB.FindAll(0, 0, "asc")
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+}
+
+func TestDeleteProductMovesItToTrash(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	p, _ := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
+	p.OwnerID = "owner-a"
+	assert.Nil(t, productDB.Create(p, "actor"))
+	assert.Nil(t, productDB.Delete(p, "actor"))
+	assert.True(t, p.IsDeleted())
+	assert.Equal(t, 2, p.Version)
+
+	pFound, err := productDB.FindDeletedById(p.ID)
+	assert.NoError(t, err)
+	assert.True(t, pFound.IsDeleted())
+	assert.Equal(t, 2, pFound.Version)
+
+	products, err := productDB.FindAllDeleted(1, 10, "")
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+
+	products, err = productDB.FindAllDeletedByOwner("owner-b", 1, 10, "")
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+
+	assert.ErrorIs(t, productDB.Update(p, "actor"), database.ErrNotFound)
+	assert.ErrorIs(t, productDB.Delete(p, "actor"), database.ErrNotFound)
+}
+
+func TestRestoreProduct(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	p, _ := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
+	assert.Nil(t, productDB.Create(p, "actor"))
+	assert.ErrorIs(t, productDB.Restore(p, "actor"), database.ErrNotFound)
+
+	assert.Nil(t, productDB.Delete(p, "actor"))
+	stale := *p
+	assert.Nil(t, productDB.Restore(p, "actor"))
+	assert.False(t, p.IsDeleted())
+	assert.Equal(t, 3, p.Version)
+
+	pFound, err := productDB.FindById(p.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, pFound.Version)
+
+	_, err = productDB.FindDeletedById(p.ID)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	assert.Nil(t, productDB.Delete(p, "actor"))
+	assert.ErrorIs(t, productDB.Restore(&stale, "actor"), database.ErrVersionConflict)
+}
+
+func TestPurgeProduct(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	p, _ := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
+	assert.Nil(t, productDB.Create(p, "actor"))
+	assert.ErrorIs(t, productDB.Purge(p.ID), database.ErrNotFound)
+
+	assert.Nil(t, productDB.Delete(p, "actor"))
+	assert.Nil(t, productDB.Purge(p.ID))
+
+	_, err := productDB.FindDeletedById(p.ID)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+	assert.ErrorIs(t, productDB.Purge(p.ID), database.ErrNotFound)
+}
+
+func TestPurgeProductsDeletedBefore(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	var products []*entity.Product
+	for i := 1; i < 4; i++ {
+		p, _ := entity.NewProduct(fmt.Sprintf("Product %d", i), money.MustParse("10.5", "USD"))
+		assert.Nil(t, productDB.Create(p, "actor"))
+		products = append(products, p)
+	}
+	assert.Nil(t, productDB.Delete(products[0], "actor"))
+	assert.Nil(t, productDB.Delete(products[1], "actor"))
+	productDB.DB.Unscoped().Model(products[0]).Update("deleted_at", time.Now().Add(-48*time.Hour))
+
+	purged, err := productDB.PurgeDeletedBefore(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	trash, err := productDB.FindAllDeleted(0, 0, "")
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	assert.Equal(t, products[1].ID, trash[0].ID)
+
+	_, err = productDB.FindById(products[2].ID)
+	assert.NoError(t, err)
+}
+
+func TestProductRevisions(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	p, _ := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
+	assert.Nil(t, productDB.Create(p, "creator"))
+	p.Price = money.MustParse("12", "USD")
+	assert.Nil(t, productDB.Update(p, "editor"))
+	assert.Nil(t, productDB.Delete(p, "editor"))
+	assert.Nil(t, productDB.Restore(p, "admin"))
+
+	revisions, err := productDB.FindRevisions(p.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 4)
+
+	for i, expected := range []struct {
+		action entity.RevisionAction
+		actor  string
+		price  string
+	}{
+		{entity.RevisionCreated, "creator", "10.50"},
+		{entity.RevisionUpdated, "editor", "12.00"},
+		{entity.RevisionDeleted, "editor", "12.00"},
+		{entity.RevisionRestored, "admin", "12.00"},
+	} {
+		assert.Equal(t, i+1, revisions[i].Number)
+		assert.Equal(t, i+1, revisions[i].Snapshot.Version)
+		assert.Equal(t, expected.action, revisions[i].Action)
+		assert.Equal(t, expected.actor, revisions[i].ActorID)
+		assert.Equal(t, expected.price, revisions[i].Snapshot.Price.Decimal())
+	}
+	assert.True(t, revisions[2].Snapshot.IsDeleted())
+	assert.False(t, revisions[3].Snapshot.IsDeleted())
+
+	revisions, err = productDB.FindRevisions(p.ID, 2, 3)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+
+	revision, err := productDB.FindRevision(p.ID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.RevisionUpdated, revision.Action)
+
+	latest, err := productDB.FindLatestRevision(p.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, latest.Number)
+
+	_, err = productDB.FindRevision(p.ID, 5)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestProductChangeRolledBackWithItsRevision(t *testing.T) {
+	productDB := makeInMemoryProductDB(t)
+
+	p, _ := entity.NewProduct("Product 1", money.MustParse("10.5", "USD"))
+	assert.Nil(t, productDB.Create(p, "creator"))
+
+	taken := entity.NewProductRevision(entity.RevisionUpdated, p, "someone")
+	taken.Number = 2
+	assert.Nil(t, productDB.DB.Create(taken).Error)
+
+	p.Name = "Product 2"
+	assert.ErrorIs(t, productDB.Update(p, "editor"), database.ErrConflict)
+	assert.Equal(t, 1, p.Version)
+
+	pFound, _ := productDB.FindById(p.ID)
+	assert.Equal(t, "Product 1", pFound.Name)
+	assert.Equal(t, 1, pFound.Version)
+}
+
+func TestMigrateProductPrices(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.Exec("CREATE TABLE products (id text PRIMARY KEY, name text, price real, owner_id text, version integer, created_at datetime, deleted_at datetime)")
+	db.Exec("INSERT INTO products (id, name, price, version) VALUES ('1', 'Product 1', 197.98, 1), ('2', 'Product 2', 0.1, 2)")
+	db.Exec("CREATE TABLE product_revisions (product_id text, number integer, action text, actor_id text, snapshot text, created_at datetime, PRIMARY KEY (product_id, number))")
+	db.Exec(`INSERT INTO product_revisions (product_id, number, snapshot) VALUES ('1', 1, '{"id":"1","name":"Product 1","price":197.98,"version":1}')`)
+
+	assert.Nil(t, database.MigrateProductPrices(db, "EUR"))
+	assert.Nil(t, db.AutoMigrate(&entity.Product{}, &entity.ProductRevision{}))
+
+	productDB := database.NewProduct(db)
+
+	p, err := productDB.FindById("1")
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("197.98", "EUR"), p.Price)
+
+	p, err = productDB.FindById("2")
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("0.10", "EUR"), p.Price)
+	assert.Equal(t, 2, p.Version)
+
+	revision, err := productDB.FindRevision("1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("197.98", "EUR"), revision.Snapshot.Price)
+	assert.Equal(t, "Product 1", revision.Snapshot.Name)
+
+	assert.False(t, db.Migrator().HasColumn(&entity.Product{}, "price"))
+	assert.False(t, db.Migrator().HasColumn(&entity.Product{}, "float_price"))
+	assert.Nil(t, database.MigrateProductPrices(db, "EUR"))
 }
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"time"
+
+	"app/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCode struct {
+	DB *gorm.DB
+}
+
+func NewRecoveryCode(db *gorm.DB) *RecoveryCode {
+	return &RecoveryCode{DB: db}
+}
+
+// ReplaceForUser drops every previous code of the user and stores the new set.
+func (rc *RecoveryCode) ReplaceForUser(userID string, codes []*entity.RecoveryCode) error {
+	err := rc.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		if len(codes) == 0 {
+			return nil
+		}
+
+		return tx.Create(codes).Error
+	})
+
+	return translateError(rc.DB, err)
+}
+
+// Use consumes an unused code of the user. It fails with ErrNotFound when no
+// such code exists.
+func (rc *RecoveryCode) Use(userID, hash string) error {
+	result := rc.DB.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return translateError(rc.DB, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (rc *RecoveryCode) CountUnused(userID string) (int64, error) {
+	var count int64
+	err := rc.DB.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, translateError(rc.DB, err)
+}
+
+func (rc *RecoveryCode) DeleteForUser(userID string) error {
+	err := rc.DB.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error
+	return translateError(rc.DB, err)
+}
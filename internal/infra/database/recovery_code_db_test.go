@@ -0,0 +1,63 @@
+package database_test
+
+import (
+	"testing"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeInMemoryRecoveryCodeDB(t *testing.T) *database.RecoveryCode {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.RecoveryCode{})
+
+	return database.NewRecoveryCode(db)
+}
+
+func TestReplaceRecoveryCodes(t *testing.T) {
+	codeDB := makeInMemoryRecoveryCodeDB(t)
+
+	codes, _, _ := entity.NewRecoveryCodes("user-id")
+	assert.Nil(t, codeDB.ReplaceForUser("user-id", codes))
+
+	count, err := codeDB.CountUnused("user-id")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(entity.RecoveryCodeCount), count)
+
+	codes, _, _ = entity.NewRecoveryCodes("user-id")
+	assert.Nil(t, codeDB.ReplaceForUser("user-id", codes[:3]))
+
+	count, _ = codeDB.CountUnused("user-id")
+	assert.Equal(t, int64(3), count)
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	codeDB := makeInMemoryRecoveryCodeDB(t)
+
+	codes, plains, _ := entity.NewRecoveryCodes("user-id")
+	assert.Nil(t, codeDB.ReplaceForUser("user-id", codes))
+
+	err := codeDB.Use("user-id", entity.HashRecoveryCode(plains[0]))
+	assert.Nil(t, err)
+
+	err = codeDB.Use("user-id", entity.HashRecoveryCode(plains[0]))
+	assert.NotNil(t, err)
+
+	err = codeDB.Use("other-user-id", entity.HashRecoveryCode(plains[1]))
+	assert.NotNil(t, err)
+
+	count, _ := codeDB.CountUnused("user-id")
+	assert.Equal(t, int64(entity.RecoveryCodeCount-1), count)
+
+	assert.Nil(t, codeDB.DeleteForUser("user-id"))
+	count, _ = codeDB.CountUnused("user-id")
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"time"
+
+	"app/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type RefreshToken struct {
+	DB *gorm.DB
+}
+
+func NewRefreshToken(db *gorm.DB) *RefreshToken {
+	return &RefreshToken{DB: db}
+}
+
+func (rt *RefreshToken) Create(token *entity.RefreshToken) error {
+	return translateError(rt.DB, rt.DB.Create(token).Error)
+}
+
+func (rt *RefreshToken) FindByHash(hash string) (*entity.RefreshToken, error) {
+	token := &entity.RefreshToken{}
+	err := rt.DB.Where("token_hash = ?", hash).First(token).Error
+	return token, translateError(rt.DB, err)
+}
+
+// Rotate revokes the current token and stores its replacement in a single
+// transaction, so a token can never be exchanged twice.
+func (rt *RefreshToken) Rotate(current *entity.RefreshToken, next *entity.RefreshToken) error {
+	err := rt.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		result := tx.Model(&entity.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{"revoked_at": now, "replaced_by": next.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		current.RevokedAt = &now
+		current.ReplacedBy = next.ID
+
+		return tx.Create(next).Error
+	})
+
+	return translateError(rt.DB, err)
+}
+
+func (rt *RefreshToken) RevokeFamily(familyID string) error {
+	err := rt.DB.Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	return translateError(rt.DB, err)
+}
+
+func (rt *RefreshToken) RevokeAllForUser(userID string) error {
+	err := rt.DB.Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	return translateError(rt.DB, err)
+}
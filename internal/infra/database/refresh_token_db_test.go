@@ -0,0 +1,81 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeInMemoryRefreshTokenDB(t *testing.T) *database.RefreshToken {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.RefreshToken{})
+
+	return database.NewRefreshToken(db)
+}
+
+func TestCreateRefreshToken(t *testing.T) {
+	tokenDB := makeInMemoryRefreshTokenDB(t)
+
+	rt, token, err := entity.NewRefreshToken("user-id", "", time.Hour)
+	assert.Nil(t, err)
+
+	err = tokenDB.Create(rt)
+	assert.Nil(t, err)
+
+	found, err := tokenDB.FindByHash(entity.HashToken(token))
+	assert.Nil(t, err)
+	assert.Equal(t, rt.ID, found.ID)
+	assert.Equal(t, rt.FamilyID, found.FamilyID)
+	assert.Nil(t, found.RevokedAt)
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	tokenDB := makeInMemoryRefreshTokenDB(t)
+
+	current, token, _ := entity.NewRefreshToken("user-id", "", time.Hour)
+	assert.Nil(t, tokenDB.Create(current))
+
+	next, _, _ := entity.NewRefreshToken("user-id", current.FamilyID, time.Hour)
+	err := tokenDB.Rotate(current, next)
+	assert.Nil(t, err)
+
+	found, err := tokenDB.FindByHash(entity.HashToken(token))
+	assert.Nil(t, err)
+	assert.True(t, found.IsRevoked())
+	assert.Equal(t, next.ID, found.ReplacedBy)
+
+	again, _, _ := entity.NewRefreshToken("user-id", current.FamilyID, time.Hour)
+	err = tokenDB.Rotate(found, again)
+	assert.NotNil(t, err)
+}
+
+func TestRevokeRefreshTokenFamily(t *testing.T) {
+	tokenDB := makeInMemoryRefreshTokenDB(t)
+
+	first, firstToken, _ := entity.NewRefreshToken("user-id", "", time.Hour)
+	second, secondToken, _ := entity.NewRefreshToken("user-id", first.FamilyID, time.Hour)
+	other, otherToken, _ := entity.NewRefreshToken("user-id", "", time.Hour)
+	assert.Nil(t, tokenDB.Create(first))
+	assert.Nil(t, tokenDB.Create(second))
+	assert.Nil(t, tokenDB.Create(other))
+
+	err := tokenDB.RevokeFamily(first.FamilyID)
+	assert.Nil(t, err)
+
+	found, _ := tokenDB.FindByHash(entity.HashToken(firstToken))
+	assert.True(t, found.IsRevoked())
+	found, _ = tokenDB.FindByHash(entity.HashToken(secondToken))
+	assert.True(t, found.IsRevoked())
+	found, _ = tokenDB.FindByHash(entity.HashToken(otherToken))
+	assert.False(t, found.IsRevoked())
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"app/internal/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRevocation struct {
+	DB *gorm.DB
+}
+
+func NewTokenRevocation(db *gorm.DB) *TokenRevocation {
+	return &TokenRevocation{DB: db}
+}
+
+func (tr *TokenRevocation) Revoke(token *entity.RevokedToken) error {
+	err := tr.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+	return translateError(tr.DB, err)
+}
+
+// RevokeAllForUser revokes the tokens of the user issued up to the given time.
+// The cutoff is kept to the millisecond, the precision of the issue time of
+// tokens, and a token issued in that same millisecond is revoked too.
+func (tr *TokenRevocation) RevokeAllForUser(userID string, before time.Time) error {
+	revocation := &entity.UserTokenRevocation{UserID: userID, RevokedBefore: before.Truncate(time.Millisecond)}
+	err := tr.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(revocation).Error
+	return translateError(tr.DB, err)
+}
+
+func (tr *TokenRevocation) IsRevoked(jti, userID string, issuedAt time.Time) (bool, error) {
+	var count int64
+	err := tr.DB.Model(&entity.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	if err != nil {
+		return false, translateError(tr.DB, err)
+	}
+
+	if count > 0 {
+		return true, nil
+	}
+
+	revocation := &entity.UserTokenRevocation{}
+	err = tr.DB.First(revocation, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, translateError(tr.DB, err)
+	}
+
+	return !issuedAt.After(revocation.RevokedBefore), nil
+}
+
+func (tr *TokenRevocation) DeleteExpired() error {
+	err := tr.DB.Where("expires_at < ?", time.Now()).Delete(&entity.RevokedToken{}).Error
+	return translateError(tr.DB, err)
+}
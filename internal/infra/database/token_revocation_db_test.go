@@ -0,0 +1,113 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func makeTokenRevocationStores(t *testing.T) map[string]database.TokenRevocationInterface {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.RevokedToken{}, &entity.UserTokenRevocation{})
+
+	return map[string]database.TokenRevocationInterface{
+		"gorm":      database.NewTokenRevocation(db),
+		"in-memory": database.NewInMemoryTokenRevocation(),
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	for name, store := range makeTokenRevocationStores(t) {
+		t.Run(name, func(t *testing.T) {
+			issuedAt := time.Now()
+
+			err := store.Revoke(entity.NewRevokedToken("jti-1", "user-id", time.Now().Add(time.Hour)))
+			assert.Nil(t, err)
+
+			err = store.Revoke(entity.NewRevokedToken("jti-1", "user-id", time.Now().Add(time.Hour)))
+			assert.Nil(t, err)
+
+			revoked, err := store.IsRevoked("jti-1", "user-id", issuedAt)
+			assert.Nil(t, err)
+			assert.True(t, revoked)
+
+			revoked, err = store.IsRevoked("jti-2", "user-id", issuedAt)
+			assert.Nil(t, err)
+			assert.False(t, revoked)
+		})
+	}
+}
+
+func TestRevokeAllTokensForUser(t *testing.T) {
+	for name, store := range makeTokenRevocationStores(t) {
+		t.Run(name, func(t *testing.T) {
+			cutoff := time.Now()
+
+			err := store.RevokeAllForUser("user-id", cutoff)
+			assert.Nil(t, err)
+
+			revoked, err := store.IsRevoked("jti-1", "user-id", cutoff.Add(-time.Minute))
+			assert.Nil(t, err)
+			assert.True(t, revoked)
+
+			revoked, err = store.IsRevoked("jti-2", "user-id", cutoff.Add(time.Minute))
+			assert.Nil(t, err)
+			assert.False(t, revoked)
+
+			revoked, err = store.IsRevoked("jti-3", "other-user-id", cutoff.Add(-time.Minute))
+			assert.Nil(t, err)
+			assert.False(t, revoked)
+		})
+	}
+}
+
+func TestRevokeAllInTheSecondATokenWasIssued(t *testing.T) {
+	for name, store := range makeTokenRevocationStores(t) {
+		t.Run(name, func(t *testing.T) {
+			second := time.Now().Truncate(time.Second)
+			issuedAt := second.Add(200 * time.Millisecond)
+			assert.Nil(t, store.RevokeAllForUser("user-id", issuedAt.Add(300*time.Microsecond)))
+
+			revoked, err := store.IsRevoked("jti-1", "user-id", issuedAt)
+			assert.Nil(t, err)
+			assert.True(t, revoked)
+
+			revoked, err = store.IsRevoked("jti-2", "user-id", issuedAt.Add(time.Millisecond))
+			assert.Nil(t, err)
+			assert.False(t, revoked)
+
+			// Tokens with only a whole-second iat claim fall back to it, and
+			// are revoked with the whole second.
+			revoked, err = store.IsRevoked("jti-3", "user-id", second)
+			assert.Nil(t, err)
+			assert.True(t, revoked)
+		})
+	}
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	for name, store := range makeTokenRevocationStores(t) {
+		t.Run(name, func(t *testing.T) {
+			assert.Nil(t, store.Revoke(entity.NewRevokedToken("expired", "user-id", time.Now().Add(-time.Hour))))
+			assert.Nil(t, store.Revoke(entity.NewRevokedToken("active", "user-id", time.Now().Add(time.Hour))))
+
+			err := store.DeleteExpired()
+			assert.Nil(t, err)
+
+			revoked, _ := store.IsRevoked("expired", "user-id", time.Now())
+			assert.False(t, revoked)
+			revoked, _ = store.IsRevoked("active", "user-id", time.Now())
+			assert.True(t, revoked)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"sync"
+	"time"
+
+	"app/internal/entity"
+)
+
+// InMemoryTokenRevocation keeps revoked tokens in process memory. It is meant
+// for tests and single instance deployments, revocations are lost on restart.
+type InMemoryTokenRevocation struct {
+	mu     sync.RWMutex
+	tokens map[string]entity.RevokedToken
+	users  map[string]time.Time
+}
+
+func NewInMemoryTokenRevocation() *InMemoryTokenRevocation {
+	return &InMemoryTokenRevocation{
+		tokens: map[string]entity.RevokedToken{},
+		users:  map[string]time.Time{},
+	}
+}
+
+func (tr *InMemoryTokenRevocation) Revoke(token *entity.RevokedToken) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.tokens[token.JTI] = *token
+	return nil
+}
+
+// RevokeAllForUser truncates the cutoff to the millisecond, as
+// TokenRevocation does.
+func (tr *InMemoryTokenRevocation) RevokeAllForUser(userID string, before time.Time) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.users[userID] = before.Truncate(time.Millisecond)
+	return nil
+}
+
+func (tr *InMemoryTokenRevocation) IsRevoked(jti, userID string, issuedAt time.Time) (bool, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	if _, ok := tr.tokens[jti]; ok {
+		return true, nil
+	}
+
+	if before, ok := tr.users[userID]; ok {
+		return !issuedAt.After(before), nil
+	}
+
+	return false, nil
+}
+
+func (tr *InMemoryTokenRevocation) DeleteExpired() error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	for jti, token := range tr.tokens {
+		if token.IsExpired() {
+			delete(tr.tokens, jti)
+		}
+	}
+
+	return nil
+}
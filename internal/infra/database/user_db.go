@@ -1,6 +1,10 @@
 package database
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"app/internal/entity"
 
 	"gorm.io/gorm"
@@ -15,11 +19,111 @@ func NewUser(db *gorm.DB) *User {
 }
 
 func (u *User) Create(user *entity.User) error {
-	return u.DB.Create(user).Error
+	err := translateError(u.DB, u.DB.Create(user).Error)
+	if errors.Is(err, ErrConflict) {
+		return ErrEmailAlreadyExists
+	}
+
+	return err
 }
 
 func (u *User) FindByEmail(email string) (*entity.User, error) {
 	user := &entity.User{}
-	err := u.DB.Where("email = ?", email).First(user).Error
-	return user, err
+	err := u.DB.Where("lower(email) = ?", entity.NormalizeEmail(email)).First(user).Error
+	return user, translateError(u.DB, err)
+}
+
+func (u *User) FindByID(id string) (*entity.User, error) {
+	user := &entity.User{}
+	err := u.DB.First(user, "id = ?", id).Error
+	return user, translateError(u.DB, err)
+}
+
+// FindAll returns a page of users ordered by email, and how many match in
+// total. A non-empty search matches a part of the name or the email.
+func (u *User) FindAll(search string, page, limit int) ([]entity.User, int64, error) {
+	query := u.DB.Model(&entity.User{})
+
+	if search = strings.TrimSpace(search); search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(search)) + "%"
+		query = query.Where(`lower(name) LIKE ? ESCAPE '\' OR lower(email) LIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, translateError(u.DB, err)
+	}
+
+	var users []entity.User
+	err := query.Order("email asc").Offset((page - 1) * limit).Limit(limit).Find(&users).Error
+	return users, total, translateError(u.DB, err)
+}
+
+// Update saves the user if it is still at user.Version, as a compare and
+// swap, and then increments user.Version. It fails with ErrVersionConflict
+// when the user was changed in the meantime, so a request holding a stale
+// copy cannot undo a concurrent change such as an admin disabling the account.
+func (u *User) Update(user *entity.User) error {
+	next := *user
+	next.Version++
+
+	result := u.DB.Model(&next).Where("version = ?", user.Version).Select("*").Updates(&next)
+	if result.Error != nil {
+		err := translateError(u.DB, result.Error)
+		if errors.Is(err, ErrConflict) {
+			return ErrEmailAlreadyExists
+		}
+		return err
+	}
+
+	if result.RowsAffected == 0 {
+		if _, err := u.FindByID(user.ID); err != nil {
+			return err
+		}
+		return ErrVersionConflict
+	}
+
+	user.Version = next.Version
+	return nil
+}
+
+func (u *User) Delete(id string) error {
+	user, err := u.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	return translateError(u.DB, u.DB.Delete(user).Error)
+}
+
+// NormalizeEmails lowercases and trims the emails stored before they were
+// normalized on signup. It runs before the unique email index is created and
+// fails when two accounts only differ by case, which has to be fixed by hand.
+func NormalizeEmails(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&entity.User{}) {
+		return nil
+	}
+
+	var duplicates []string
+	err := db.Model(&entity.User{}).
+		Select("lower(trim(email))").
+		Group("lower(trim(email))").
+		Having("count(*) > 1").
+		Pluck("lower(trim(email))", &duplicates).Error
+	if err != nil {
+		return err
+	}
+
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%w: %s", ErrEmailAlreadyExists, strings.Join(duplicates, ", "))
+	}
+
+	return db.Model(&entity.User{}).
+		Where("email <> lower(trim(email))").
+		Update("email", gorm.Expr("lower(trim(email))")).Error
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern so they match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
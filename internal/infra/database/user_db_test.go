@@ -66,3 +66,202 @@ func TestFindByEmail(t *testing.T) {
 	assert.Equal(t, user.Email, userFound.Email)
 	assert.Equal(t, user.Password, userFound.Password)
 }
+
+func TestFindUserByID(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.User{})
+
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPass)
+	userDB := database.NewUser(db)
+
+	err = userDB.Create(user)
+	assert.Nil(t, err)
+
+	userFound, err := userDB.FindByID(user.ID)
+	assert.Nil(t, err)
+
+	assert.Equal(t, user.ID, userFound.ID)
+	assert.Equal(t, user.Email, userFound.Email)
+	assert.Equal(t, entity.RoleViewer, userFound.Role)
+
+	_, err = userDB.FindByID("missing")
+	assert.NotNil(t, err)
+}
+
+func TestUpdateUser(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.User{})
+
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPass)
+	userDB := database.NewUser(db)
+
+	err = userDB.Create(user)
+	assert.Nil(t, err)
+
+	user.Name = "Jane Doe"
+	user.Email = "jane@doe.com"
+	err = userDB.Update(user)
+	assert.Nil(t, err)
+
+	userFound, err := userDB.FindByID(user.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jane Doe", userFound.Name)
+	assert.Equal(t, "jane@doe.com", userFound.Email)
+
+	missing, _ := entity.NewUser(fakeUserName, "missing@doe.com", fakeUserPass)
+	err = userDB.Update(missing)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestUpdateUserVersionConflict(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.User{})
+
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPass)
+	userDB := database.NewUser(db)
+	assert.Nil(t, userDB.Create(user))
+
+	stale := *user
+	user.Disable()
+	assert.Nil(t, userDB.Update(user))
+	assert.Equal(t, 2, user.Version)
+
+	stale.Name = "Jane Doe"
+	err = userDB.Update(&stale)
+	assert.ErrorIs(t, err, database.ErrVersionConflict)
+	assert.Equal(t, 1, stale.Version)
+
+	userFound, _ := userDB.FindByID(user.ID)
+	assert.True(t, userFound.IsDisabled())
+	assert.Equal(t, fakeUserName, userFound.Name)
+}
+
+func TestDeleteUser(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.User{})
+
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPass)
+	userDB := database.NewUser(db)
+
+	err = userDB.Create(user)
+	assert.Nil(t, err)
+
+	err = userDB.Delete(user.ID)
+	assert.Nil(t, err)
+
+	_, err = userDB.FindByID(user.ID)
+	assert.NotNil(t, err)
+
+	err = userDB.Delete(user.ID)
+	assert.NotNil(t, err)
+}
+
+func TestFindAllUsers(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.User{})
+
+	userDB := database.NewUser(db)
+	for _, u := range [][2]string{
+		{"John Doe", "john@doe.com"},
+		{"Jane Doe", "jane@doe.com"},
+		{"Mary Smith", "mary_smith@example.com"},
+		{"Marysmith", "marysmith@example.com"},
+	} {
+		user, _ := entity.NewUser(u[0], u[1], fakeUserPass)
+		assert.Nil(t, userDB.Create(user))
+	}
+
+	users, total, err := userDB.FindAll("", 1, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Len(t, users, 3)
+	assert.Equal(t, "jane@doe.com", users[0].Email)
+
+	users, total, err = userDB.FindAll("", 2, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Len(t, users, 1)
+
+	users, total, err = userDB.FindAll("DOE", 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, users, 2)
+
+	users, total, err = userDB.FindAll("mary_", 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "mary_smith@example.com", users[0].Email)
+}
+
+func TestCreateUserWithDuplicatedEmail(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.User{})
+
+	user, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPass)
+	userDB := database.NewUser(db)
+
+	err = userDB.Create(user)
+	assert.Nil(t, err)
+
+	duplicated, _ := entity.NewUser(fakeUserName, fakeUserEmail, fakeUserPass)
+	duplicated.Email = "JOHN@doe.com"
+	err = userDB.Create(duplicated)
+	assert.Equal(t, database.ErrEmailAlreadyExists, err)
+
+	userFound, err := userDB.FindByEmail(" John@Doe.com")
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, userFound.ID)
+}
+
+func TestNormalizeEmails(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.Exec("CREATE TABLE users (id text, name text, email text, password text)")
+	db.Exec("INSERT INTO users (id, email) VALUES ('1', ' John@Doe.com'), ('2', 'jane@doe.com')")
+
+	err = database.NormalizeEmails(db)
+	assert.Nil(t, err)
+
+	var emails []string
+	db.Table("users").Order("id").Pluck("email", &emails)
+	assert.Equal(t, []string{"john@doe.com", "jane@doe.com"}, emails)
+
+	db.Exec("INSERT INTO users (id, email) VALUES ('3', 'JANE@doe.com')")
+
+	err = database.NormalizeEmails(db)
+	assert.ErrorIs(t, err, database.ErrEmailAlreadyExists)
+}
@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogMailer writes every message to an io.Writer instead of delivering it. It
+// is meant for local development, where the writer is stdout or a file.
+type LogMailer struct {
+	mu   sync.Mutex
+	From string
+	Out  io.Writer
+}
+
+func NewLogMailer(from string, out io.Writer) *LogMailer {
+	return &LogMailer{From: from, Out: out}
+}
+
+func (m *LogMailer) Send(message Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(
+		m.Out,
+		"Date: %s\r\nFrom: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n.\r\n",
+		time.Now().Format(time.RFC1123Z),
+		m.From,
+		message.To,
+		message.Subject,
+		message.Body,
+	)
+
+	return err
+}
@@ -0,0 +1,22 @@
+package mail_test
+
+import (
+	"bytes"
+	"testing"
+
+	"app/internal/infra/mail"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailerSend(t *testing.T) {
+	var out bytes.Buffer
+	mailer := mail.NewLogMailer("no-reply@domain.com", &out)
+
+	err := mailer.Send(mail.Message{To: "john@doe.com", Subject: "Hello", Body: "token"})
+	assert.Nil(t, err)
+	assert.Contains(t, out.String(), "From: no-reply@domain.com")
+	assert.Contains(t, out.String(), "To: john@doe.com")
+	assert.Contains(t, out.String(), "Subject: Hello")
+	assert.Contains(t, out.String(), "token")
+}
@@ -0,0 +1,112 @@
+package mail_test
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"app/internal/infra/mail"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSMTPServer struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+// startFakeSMTPServer accepts a single connection and speaks just enough SMTP
+// for net/smtp to deliver one message.
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+
+	go func() {
+		defer close(server.done)
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost fake smtp")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				server.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				server.to = append(server.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				server.data = data.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return server
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	defer server.listener.Close()
+
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	mailer := mail.NewSMTPMailer(host, port, "", "", "no-reply@domain.com")
+
+	err := mailer.Send(mail.Message{
+		To:      "john@doe.com",
+		Subject: "Hello\r\nBcc: evil@domain.com",
+		Body:    "first line\nsecond line",
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	<-server.done
+
+	assert.Equal(t, "no-reply@domain.com", server.from)
+	assert.Equal(t, []string{"john@doe.com"}, server.to)
+	assert.Contains(t, server.data, "To: john@doe.com\r\n")
+	assert.Contains(t, server.data, "Subject: HelloBcc: evil@domain.com\r\n")
+	assert.Contains(t, server.data, "\r\n\r\nfirst line\r\nsecond line")
+}
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+	utils "app/pkg/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxUserPageLimit = 100
+
+type AdminUserHandler struct {
+	UserDB            database.UserInterface
+	RefreshTokenDB    database.RefreshTokenInterface
+	TokenRevocationDB database.TokenRevocationInterface
+	PasswordReset     *PasswordResetHandler
+	Audit             *Auditor
+}
+
+func NewAdminUserHandler(
+	UserDB database.UserInterface,
+	RefreshTokenDB database.RefreshTokenInterface,
+	TokenRevocationDB database.TokenRevocationInterface,
+	PasswordReset *PasswordResetHandler,
+	Audit *Auditor,
+) *AdminUserHandler {
+	return &AdminUserHandler{
+		UserDB,
+		RefreshTokenDB,
+		TokenRevocationDB,
+		PasswordReset,
+		Audit,
+	}
+}
+
+// ListUsers godoc
+// @Summary      List users
+// @Description  List users ordered by email, optionally searching a part of the name or email
+// @Tags         admin
+// @Produce      json
+// @Param        search  query     string  false  "part of the name or email"
+// @Param        page    query     string  false  "page number"
+// @Param        limit   query     string  false  "limit, at most 100"
+// @Success      200  {object}  dto.AdminUserPageOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/users [get]
+// @Security ApiKeyAuth
+func (h *AdminUserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > maxUserPageLimit {
+		limit = maxUserPageLimit
+	}
+
+	users, total, err := h.UserDB.FindAll(query.Get("search"), page, limit)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	items := make([]dto.AdminUserOutput, 0, len(users))
+	for i := range users {
+		items = append(items, newAdminUserOutput(&users[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.AdminUserPageOutput{
+		Items: items,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+// GetUser godoc
+// @Summary      Get user
+// @Description  Get the account details of a user
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "user ID" Format(uuid)
+// @Success      200  {object}  dto.AdminUserOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Router       /api/v1/admin/users/{id} [get]
+// @Security ApiKeyAuth
+func (h *AdminUserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newAdminUserOutput(u))
+}
+
+// DisableUser godoc
+// @Summary      Disable user
+// @Description  Block the logins of a user and end all of their sessions
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "user ID" Format(uuid)
+// @Success      200  {object}  dto.AdminUserOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/users/{id}/disable [post]
+// @Security ApiKeyAuth
+func (h *AdminUserHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.findOtherUser(w, r, "disable")
+	if !ok {
+		return
+	}
+
+	before := *u
+	u.Disable()
+
+	err := h.UserDB.Update(u)
+	if err == nil {
+		err = revokeSessions(h.TokenRevocationDB, h.RefreshTokenDB, u.ID)
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	h.auditUserChange(r, entity.AuditUserDisabled, &before, u)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newAdminUserOutput(u))
+}
+
+// EnableUser godoc
+// @Summary      Enable user
+// @Description  Allow a disabled user to log in again
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "user ID" Format(uuid)
+// @Success      200  {object}  dto.AdminUserOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/users/{id}/enable [post]
+// @Security ApiKeyAuth
+func (h *AdminUserHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	before := *u
+	u.Enable()
+
+	if err := h.UserDB.Update(u); err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	h.auditUserChange(r, entity.AuditUserEnabled, &before, u)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newAdminUserOutput(u))
+}
+
+// ForcePasswordReset godoc
+// @Summary      Force a password reset
+// @Description  Block logins with the current password, end all sessions and email a password reset token to the user
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "user ID" Format(uuid)
+// @Success      200  {object}  dto.AdminUserOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/users/{id}/password-reset [post]
+// @Security ApiKeyAuth
+func (h *AdminUserHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	before := *u
+	u.RequirePasswordReset()
+
+	err := h.UserDB.Update(u)
+	if err == nil {
+		err = revokeSessions(h.TokenRevocationDB, h.RefreshTokenDB, u.ID)
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	if err := h.PasswordReset.SendPasswordReset(u); err != nil {
+		log.Printf("password reset for user %s: %v", u.ID, err)
+	}
+
+	h.auditUserChange(r, entity.AuditUserPasswordResetForced, &before, u)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newAdminUserOutput(u))
+}
+
+// ChangeUserRole godoc
+// @Summary      Change user role
+// @Description  Change the role of a user and end all of their sessions, since tokens carry the role they were issued with
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string                   true  "user ID" Format(uuid)
+// @Param        request   body      dto.UpdateUserRoleInput  true  "viewer, editor or admin"
+// @Success      200  {object}  dto.AdminUserOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/users/{id}/role [put]
+// @Security ApiKeyAuth
+func (h *AdminUserHandler) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	var input dto.UpdateUserRoleInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	u, ok := h.findOtherUser(w, r, "change the role of")
+	if !ok {
+		return
+	}
+
+	before := *u
+	if err := u.SetRole(entity.Role(input.Role)); err != nil {
+		writeValidationProblem(w, r, err, nil)
+		return
+	}
+
+	err := h.UserDB.Update(u)
+	if err == nil {
+		err = revokeSessions(h.TokenRevocationDB, h.RefreshTokenDB, u.ID)
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	h.auditUserChange(r, entity.AuditUserRoleChanged, &before, u)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newAdminUserOutput(u))
+}
+
+// DeleteUser godoc
+// @Summary      Delete user
+// @Description  Delete a user and end all of their sessions
+// @Tags         admin
+// @Param        id   path      string  true  "user ID" Format(uuid)
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/users/{id} [delete]
+// @Security ApiKeyAuth
+func (h *AdminUserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.findOtherUser(w, r, "delete")
+	if !ok {
+		return
+	}
+
+	err := h.UserDB.Delete(u.ID)
+	if err == nil {
+		err = revokeSessions(h.TokenRevocationDB, h.RefreshTokenDB, u.ID)
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	h.auditUserChange(r, entity.AuditUserDeleted, u, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminUserHandler) findUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return nil, false
+	}
+
+	u, err := h.UserDB.FindByID(id)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return nil, false
+	}
+
+	return u, true
+}
+
+// findOtherUser is findUser for the actions an admin may not take on their
+// own account, which could leave the service without an admin.
+func (h *AdminUserHandler) findOtherUser(w http.ResponseWriter, r *http.Request, action string) (*entity.User, bool) {
+	u, ok := h.findUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if adminID, _ := currentUser(r); u.ID == adminID {
+		writeProblem(w, r, http.StatusConflict, "You cannot "+action+" your own account")
+		return nil, false
+	}
+
+	return u, true
+}
+
+// auditUserChange records an admin action on a user with the account before
+// and after it. After is nil for deletions.
+func (h *AdminUserHandler) auditUserChange(r *http.Request, action entity.AuditAction, before, after *entity.User) {
+	event := entity.NewAuditEvent(action, "user", before.ID)
+
+	var afterSnapshot interface{}
+	if after != nil {
+		afterSnapshot = newAdminUserOutput(after)
+	}
+	if err := event.SetSnapshots(newAdminUserOutput(before), afterSnapshot); err != nil {
+		log.Printf("audit snapshot of user %s: %v", before.ID, err)
+	}
+
+	h.Audit.Record(r, event)
+}
+
+func newAdminUserOutput(u *entity.User) dto.AdminUserOutput {
+	return dto.AdminUserOutput{
+		ID:                    u.ID,
+		Name:                  u.Name,
+		Email:                 u.Email,
+		Role:                  string(u.Role),
+		EmailVerifiedAt:       u.EmailVerifiedAt,
+		TotpEnabled:           u.TotpEnabled,
+		DisabledAt:            u.DisabledAt,
+		PasswordResetRequired: u.PasswordResetRequired,
+	}
+}
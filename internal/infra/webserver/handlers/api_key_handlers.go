@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+	utils "app/pkg/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type APIKeyHandler struct {
+	APIKeyDB database.APIKeyInterface
+}
+
+func NewAPIKeyHandler(APIKeyDB database.APIKeyInterface) *APIKeyHandler {
+	return &APIKeyHandler{APIKeyDB}
+}
+
+// CreateAPIKey godoc
+// @Summary      Create API key
+// @Description  Create a named API key acting for the authenticated user within the given scopes. The key is only returned once.
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.CreateAPIKeyInput  true  "key name, scopes and optional expiry"
+// @Success      201  {object}  dto.CreateAPIKeyOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/me/api-keys [post]
+// @Security ApiKeyAuth
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var input dto.CreateAPIKeyInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	userID, _ := currentUser(r)
+
+	scopes := make([]entity.Scope, 0, len(input.Scopes))
+	for _, s := range input.Scopes {
+		scopes = append(scopes, entity.Scope(s))
+	}
+
+	k, key, err := entity.NewAPIKey(userID, input.Name, scopes, input.ExpiresAt)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.APIKeyDB.Create(k)
+	if err != nil {
+		writeRepositoryError(w, r, err, "API key")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CreateAPIKeyOutput{APIKeyOutput: newAPIKeyOutput(k), Key: key})
+}
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  List the API keys of the authenticated user, revoked ones included
+// @Tags         api-keys
+// @Produce      json
+// @Success      200  {array}   dto.APIKeyOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/me/api-keys [get]
+// @Security ApiKeyAuth
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, _ := currentUser(r)
+
+	keys, err := h.APIKeyDB.FindAllByUser(userID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "API key")
+		return
+	}
+
+	output := make([]dto.APIKeyOutput, 0, len(keys))
+	for i := range keys {
+		output = append(output, newAPIKeyOutput(&keys[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke API key
+// @Description  Revoke an API key of the authenticated user
+// @Tags         api-keys
+// @Param        id   path      string  true  "api key ID" Format(uuid)
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/me/api-keys/{id} [delete]
+// @Security ApiKeyAuth
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	userID, _ := currentUser(r)
+
+	err := h.APIKeyDB.Revoke(id, userID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "API key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newAPIKeyOutput(k *entity.APIKey) dto.APIKeyOutput {
+	scopes := make([]string, 0)
+	for _, s := range k.ScopeList() {
+		scopes = append(scopes, string(s))
+	}
+
+	return dto.APIKeyOutput{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+)
+
+const maxAuditPageLimit = 100
+
+type AuditHandler struct {
+	AuditDB database.AuditLogInterface
+}
+
+func NewAuditHandler(AuditDB database.AuditLogInterface) *AuditHandler {
+	return &AuditHandler{AuditDB}
+}
+
+// ListAuditEvents godoc
+// @Summary      List audit events
+// @Description  Query the audit log, newest first
+// @Tags         admin
+// @Produce      json
+// @Param        action         query     string  false  "action, e.g. login.failed"
+// @Param        actor_id       query     string  false  "actor ID"
+// @Param        resource_type  query     string  false  "resource type, user or product"
+// @Param        resource_id    query     string  false  "resource ID"
+// @Param        from           query     string  false  "earliest time, RFC 3339"
+// @Param        to             query     string  false  "latest time excluded, RFC 3339"
+// @Param        page           query     string  false  "page number"
+// @Param        limit          query     string  false  "limit, at most 100"
+// @Success      200  {object}  dto.AuditEventPageOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/audit-events [get]
+// @Security ApiKeyAuth
+func (h *AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := database.AuditEventFilter{
+		Action:       entity.AuditAction(query.Get("action")),
+		ActorID:      query.Get("actor_id"),
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+	}
+
+	var err error
+	filter.From, err = parseTimeParam(query.Get("from"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid from, expected RFC 3339 time")
+		return
+	}
+
+	filter.To, err = parseTimeParam(query.Get("to"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid to, expected RFC 3339 time")
+		return
+	}
+
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > maxAuditPageLimit {
+		limit = maxAuditPageLimit
+	}
+
+	events, total, err := h.AuditDB.Find(filter, page, limit)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Audit event")
+		return
+	}
+
+	if events == nil {
+		events = []entity.AuditEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.AuditEventPageOutput{
+		Items: events,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth"
+)
+
+// Auditor appends events to the audit log, completed with the actor and the
+// client of the request. Failing to write an event is logged and does not
+// fail the request.
+type Auditor struct {
+	AuditDB database.AuditLogInterface
+}
+
+func NewAuditor(AuditDB database.AuditLogInterface) *Auditor {
+	return &Auditor{AuditDB}
+}
+
+// Record fills in who made the request, unless the event already names its
+// actor, and appends it.
+func (a *Auditor) Record(r *http.Request, event *entity.AuditEvent) {
+	if event.ActorID == "" {
+		event.ActorID, event.ActorType = requestActor(r)
+	}
+	if event.ActorType == "" {
+		event.ActorType = entity.ActorUser
+	}
+
+	event.IP = clientIP(r)
+	event.UserAgent = r.UserAgent()
+	event.RequestID = middleware.GetReqID(r.Context())
+
+	if err := a.AuditDB.Append(event); err != nil {
+		log.Printf("audit event %s %s: %v", event.Action, event.ResourceID, err)
+	}
+}
+
+// RecordLogin records a login attempt. Failed attempts may not know the user,
+// the attempted email is kept in the details.
+func (a *Auditor) RecordLogin(r *http.Request, action entity.AuditAction, userID, email, reason string) {
+	event := entity.NewAuditEvent(action, "user", userID)
+	event.ActorID = userID
+	event.ActorType = entity.ActorUser
+	if userID == "" {
+		event.ActorType = entity.ActorAnonymous
+	}
+
+	event.Details = "email=" + email
+	if reason != "" {
+		event.Details += " reason=" + reason
+	}
+
+	a.Record(r, event)
+}
+
+func requestActor(r *http.Request) (string, string) {
+	token, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil || token == nil {
+		return "", entity.ActorAnonymous
+	}
+
+	typ, _ := claims["typ"].(string)
+	switch typ {
+	case entity.TokenTypeAPIKey:
+		return token.Subject(), entity.ActorAPIKey
+	case entity.TokenTypeClient:
+		return token.Subject(), entity.ActorClient
+	default:
+		return token.Subject(), entity.ActorUser
+	}
+}
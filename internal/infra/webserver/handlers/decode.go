@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"app/pkg/money"
+	"app/pkg/problem"
+)
+
+// bodyError is a request body refused by decodeJSONBody, with the status code
+// to answer and, for a single offending field, its name and rule.
+type bodyError struct {
+	status int
+	detail string
+	field  *problem.FieldError
+}
+
+func (e *bodyError) Error() string {
+	return e.detail
+}
+
+// decodeJSON reads the request body into v. It answers with a problem and
+// returns false unless the body is exactly one JSON value of the shape of v.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := decodeJSONBody(r, v, "application/json"); err != nil {
+		writeBodyError(w, r, err)
+		return false
+	}
+
+	return true
+}
+
+// decodeJSONBody decodes a body of one of the given media types into v,
+// rejecting fields v does not have and anything after the first JSON value.
+// Bodies over the limit set by middlewares.LimitBodySize fail with 413.
+func decodeJSONBody(r *http.Request, v interface{}, mediaTypes ...string) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !slices.Contains(mediaTypes, mediaType) {
+		return &bodyError{
+			status: http.StatusUnsupportedMediaType,
+			detail: "Content-Type must be " + strings.Join(mediaTypes, " or "),
+		}
+	}
+
+	return decodeStrict(json.NewDecoder(r.Body), v)
+}
+
+// unmarshalStrict decodes data into v as decodeJSONBody decodes bodies, for
+// documents built from the request such as a patched resource.
+func unmarshalStrict(data []byte, v interface{}) error {
+	return decodeStrict(json.NewDecoder(bytes.NewReader(data)), v)
+}
+
+func decodeStrict(dec *json.Decoder, v interface{}) error {
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if err := dec.Decode(&struct{}{}); errors.As(err, &maxBytesErr) {
+		return decodeError(err)
+	} else if !errors.Is(err, io.EOF) {
+		return &bodyError{status: http.StatusBadRequest, detail: "Request body must contain a single JSON value"}
+	}
+
+	return nil
+}
+
+// decodeError describes why json.Decoder failed in terms of the request.
+func decodeError(err error) *bodyError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &bodyError{
+			status: http.StatusRequestEntityTooLarge,
+			detail: fmt.Sprintf("Request body must not exceed %d bytes", maxBytesErr.Limit),
+		}
+	case errors.Is(err, io.EOF):
+		return &bodyError{status: http.StatusBadRequest, detail: "Request body is empty"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &bodyError{status: http.StatusBadRequest, detail: "Request body is not valid JSON: unexpected end of input"}
+	case errors.As(err, &syntaxErr):
+		return &bodyError{
+			status: http.StatusBadRequest,
+			detail: fmt.Sprintf("Request body is not valid JSON at offset %d", syntaxErr.Offset),
+		}
+	case errors.As(err, &typeErr):
+		expected := "expected " + jsonKind(typeErr.Type)
+		if typeErr.Field == "" {
+			return &bodyError{status: http.StatusBadRequest, detail: "Request body: " + expected}
+		}
+		return &bodyError{
+			status: http.StatusBadRequest,
+			detail: fmt.Sprintf("field %s: %s", typeErr.Field, expected),
+			field:  &problem.FieldError{Field: typeErr.Field, Rule: "type", Message: expected},
+		}
+	case errors.Is(err, money.ErrInvalidAmount), errors.Is(err, money.ErrUnknownCurrency):
+		// Money is only decoded from request bodies as the price of a
+		// product, and its unmarshaler cannot tell the field it is in.
+		rule := "amount"
+		if errors.Is(err, money.ErrUnknownCurrency) {
+			rule = "currency"
+		}
+		return &bodyError{
+			status: http.StatusBadRequest,
+			detail: "field price: " + err.Error(),
+			field:  &problem.FieldError{Field: "price", Rule: rule, Message: err.Error()},
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &bodyError{
+			status: http.StatusBadRequest,
+			detail: fmt.Sprintf("field %s: unknown field", field),
+			field:  &problem.FieldError{Field: field, Rule: "unknown", Message: "unknown field"},
+		}
+	}
+
+	// Errors of custom unmarshalers, such as a malformed time, name no field.
+	return &bodyError{status: http.StatusBadRequest, detail: "Request body is invalid: " + err.Error()}
+}
+
+// jsonKind names the JSON type a Go value is decoded from.
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func writeBodyError(w http.ResponseWriter, r *http.Request, err error) {
+	var bodyErr *bodyError
+	if !errors.As(err, &bodyErr) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if bodyErr.field != nil {
+		problem.Validation(r, bodyErr.detail, []problem.FieldError{*bodyErr.field}).Write(w)
+		return
+	}
+
+	writeProblem(w, r, bodyErr.status, bodyErr.detail)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+	"app/internal/infra/mail"
+)
+
+type EmailVerificationHandler struct {
+	UserDB                   database.UserInterface
+	EmailVerificationTokenDB database.EmailVerificationTokenInterface
+	Mailer                   mail.Mailer
+	LoginThrottler           *LoginThrottler
+	ExpiresIn                int
+	AppURL                   string
+}
+
+func NewEmailVerificationHandler(
+	UserDB database.UserInterface,
+	EmailVerificationTokenDB database.EmailVerificationTokenInterface,
+	Mailer mail.Mailer,
+	LoginThrottler *LoginThrottler,
+	ExpiresIn int,
+	AppURL string,
+) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		UserDB,
+		EmailVerificationTokenDB,
+		Mailer,
+		LoginThrottler,
+		ExpiresIn,
+		AppURL,
+	}
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email
+// @Description  Confirm the email of a user with the token sent on signup
+// @Tags         users
+// @Produce      json
+// @Param        token   query     string  true  "email verification token"
+// @Success      200  {object}  dto.MessageOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/verify [get]
+func (h *EmailVerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	plain := r.URL.Query().Get("token")
+	if plain == "" {
+		writeProblem(w, r, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	token, err := h.EmailVerificationTokenDB.FindByHash(entity.HashToken(plain))
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "Token")
+		return
+	}
+	if err != nil || token.IsUsed() || token.IsExpired() {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	u, err := h.UserDB.FindByID(token.UserID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	err = h.EmailVerificationTokenDB.MarkUsed(token)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "Token")
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	u.VerifyEmail()
+
+	err = h.UserDB.Update(u)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.MessageOutput{Message: "Email verified"})
+}
+
+// ResendVerification godoc
+// @Summary      Resend verification email
+// @Description  Send a new email verification token. The response is the same whether the email exists or not. Requests count against the login attempts of the client IP.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.ResendVerificationInput  true  "account email"
+// @Success      202  {object}  dto.MessageOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/verify/resend [post]
+func (h *EmailVerificationHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	// Throttled like password reset requests.
+	wait, err := h.LoginThrottler.CheckIP(clientIP(r))
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if wait > 0 {
+		setRetryAfter(w, wait)
+		writeProblem(w, r, http.StatusTooManyRequests, "Too many requests")
+		return
+	}
+
+	var input dto.ResendVerificationInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	if input.Email == "" {
+		writeRequiredField(w, r, "email")
+		return
+	}
+
+	// Sent in the background like password resets, so the response time does
+	// not tell which emails are registered.
+	if u, err := h.UserDB.FindByEmail(input.Email); err == nil && !u.IsEmailVerified() {
+		go func() {
+			if err := h.SendVerificationEmail(u); err != nil {
+				log.Printf("email verification for user %s: %v", u.ID, err)
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dto.MessageOutput{
+		Message: "If the email is registered and not verified yet, a verification email has been sent",
+	})
+}
+
+// SendVerificationEmail stores a new verification token for the user and
+// mails the link that confirms it.
+func (h *EmailVerificationHandler) SendVerificationEmail(u *entity.User) error {
+	token, plain, err := entity.NewEmailVerificationToken(u.ID, time.Second*time.Duration(h.ExpiresIn))
+	if err != nil {
+		return err
+	}
+
+	err = h.EmailVerificationTokenDB.Create(token)
+	if err != nil {
+		return err
+	}
+
+	return h.Mailer.Send(mail.Message{
+		To:      u.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nConfirm your email by opening the link below. It expires in %d hours.\n\n%s/api/v1/users/verify?token=%s\n",
+			u.Name,
+			h.ExpiresIn/3600,
+			h.AppURL,
+			url.QueryEscape(plain),
+		),
+	})
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// versionETag is the strong entity tag of a resource version.
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagMatches evaluates an If-Match or If-None-Match header, a list of entity
+// tags or "*", against the current etag. If-Match compares strongly, so weak
+// tags never match, while If-None-Match compares weakly and ignores the W/
+// prefix.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"app/pkg/jwtkeys"
+)
+
+type JwksHandler struct {
+	Keys *jwtkeys.KeyRing
+}
+
+func NewJwksHandler(Keys *jwtkeys.KeyRing) *JwksHandler {
+	return &JwksHandler{Keys}
+}
+
+// GetJwks godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys verifying the tokens issued by the API, including keys retired by a rotation whose tokens may still be valid. Empty when tokens are signed with a shared secret.
+// @Tags         keys
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  problem.Problem
+// @Router       /.well-known/jwks.json [get]
+func (h *JwksHandler) GetJwks(w http.ResponseWriter, r *http.Request) {
+	set, err := json.Marshal(h.Keys.PublicSet())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error encoding keys")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(set)
+}
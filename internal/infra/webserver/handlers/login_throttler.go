@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+)
+
+// LoginThrottler slows down password guessing on generate-jwt, per account
+// with an exponential backoff and lockout, and per client IP with a sliding
+// window. The IP window also limits the requests that send emails.
+type LoginThrottler struct {
+	Store  database.LoginThrottleInterface
+	Policy entity.LoginThrottlePolicy
+}
+
+func NewLoginThrottler(store database.LoginThrottleInterface, policy entity.LoginThrottlePolicy) *LoginThrottler {
+	return &LoginThrottler{
+		Store:  store,
+		Policy: policy,
+	}
+}
+
+// CheckIP records an attempt from the IP and returns how long it has to wait
+// when the window is already full.
+func (t *LoginThrottler) CheckIP(ip string) (time.Duration, error) {
+	if t.Policy.IPMaxAttempts <= 0 || t.Policy.IPWindow <= 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	windowStart := now.Add(-t.Policy.IPWindow)
+
+	err := t.Store.DeleteIPAttemptsBefore(windowStart)
+	if err != nil {
+		return 0, err
+	}
+
+	recorded, err := t.Store.RecordIPAttempt(ip, now, windowStart, t.Policy.IPMaxAttempts)
+	if err != nil || recorded {
+		return 0, err
+	}
+
+	attempts, err := t.Store.IPAttemptsSince(ip, windowStart)
+	if err != nil {
+		return 0, err
+	}
+
+	// The oldest attempts may have left the window since the insert was refused.
+	if len(attempts) < t.Policy.IPMaxAttempts {
+		return time.Second, nil
+	}
+
+	oldest := attempts[len(attempts)-t.Policy.IPMaxAttempts]
+	return oldest.Add(t.Policy.IPWindow).Sub(now), nil
+}
+
+// CheckAccount returns how long the email has to wait before its credentials
+// are checked again. When it does not have to wait, the attempt is counted as
+// a failure at once, atomically, until Success clears it, so parallel guesses
+// are held to the same limit as sequential ones.
+func (t *LoginThrottler) CheckAccount(email string) (time.Duration, error) {
+	now := time.Now()
+
+	var allowed bool
+	lockout, err := t.Store.UpdateAccountLockout(email, func(lockout *entity.AccountLockout) bool {
+		allowed = lockout.Attempt(t.Policy, now)
+		return allowed
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if allowed {
+		return 0, nil
+	}
+	return lockout.RetryAfter(now), nil
+}
+
+// Failure returns the wait imposed after a failed login for the email, which
+// CheckAccount already counted.
+func (t *LoginThrottler) Failure(email string) (time.Duration, error) {
+	lockout, err := t.Store.FindAccountLockout(email)
+	if err != nil {
+		return 0, err
+	}
+
+	return lockout.RetryAfter(time.Now()), nil
+}
+
+func (t *LoginThrottler) Success(email string) error {
+	return t.Store.DeleteAccountLockout(email)
+}
+
+// setRetryAfter writes the wait as whole seconds, rounding up so clients never
+// retry too early.
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	if wait <= 0 {
+		return
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
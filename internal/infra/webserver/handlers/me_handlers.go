@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+)
+
+// GetMe godoc
+// @Summary      Get current user
+// @Description  Get the profile of the authenticated user
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  dto.UserOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Router       /api/v1/users/me [get]
+// @Security ApiKeyAuth
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, _ := currentUser(r)
+
+	u, err := h.UserDB.FindByID(userID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newUserOutput(u))
+}
+
+// UpdateMe godoc
+// @Summary      Update current user
+// @Description  Change the name or email of the authenticated user. A new email has to be verified again.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.UpdateMeInput  true  "profile fields"
+// @Success      200  {object}  dto.UserOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/me [patch]
+// @Security ApiKeyAuth
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var input dto.UpdateMeInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	userID, _ := currentUser(r)
+
+	u, err := h.UserDB.FindByID(userID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	if input.Name != nil {
+		u.Name = *input.Name
+	}
+
+	emailChanged := input.Email != nil && entity.NormalizeEmail(*input.Email) != u.Email
+	if emailChanged {
+		u.Email = entity.NormalizeEmail(*input.Email)
+		u.EmailVerifiedAt = nil
+	}
+
+	if err := u.Validate(); err != nil {
+		writeValidationProblem(w, r, err, nil)
+		return
+	}
+
+	err = h.UserDB.Update(u)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	if emailChanged {
+		if err := h.EmailVerification.SendVerificationEmail(u); err != nil {
+			log.Printf("email verification for user %s: %v", u.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newUserOutput(u))
+}
+
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Change the password of the authenticated user and end all of their sessions
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.ChangePasswordInput  true  "current and new password"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/me/password [put]
+// @Security ApiKeyAuth
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var input dto.ChangePasswordInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	if input.NewPassword == "" {
+		writeRequiredField(w, r, "new_password")
+		return
+	}
+
+	userID, _ := currentUser(r)
+
+	u, err := h.UserDB.FindByID(userID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	if !u.ValidatePassword(input.CurrentPassword) {
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid current password")
+		return
+	}
+
+	err = u.ChangePassword(input.NewPassword)
+	if err != nil {
+		writeValidationProblem(w, r, err, newPasswordField)
+		return
+	}
+
+	err = h.UserDB.Update(u)
+	if err == nil {
+		err = revokeSessions(h.TokenRevocationDB, h.RefreshTokenDB, u.ID)
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteMe godoc
+// @Summary      Delete current user
+// @Description  Close the account of the authenticated user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.DeleteMeInput  true  "current password"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/me [delete]
+// @Security ApiKeyAuth
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	var input dto.DeleteMeInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	userID, _ := currentUser(r)
+
+	u, err := h.UserDB.FindByID(userID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	if !u.ValidatePassword(input.Password) {
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+
+	err = h.UserDB.Delete(u.ID)
+	if err == nil {
+		err = revokeSessions(h.TokenRevocationDB, h.RefreshTokenDB, u.ID)
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSessions invalidates every access and refresh token of the user.
+func revokeSessions(
+	tokenRevocationDB database.TokenRevocationInterface,
+	refreshTokenDB database.RefreshTokenInterface,
+	userID string,
+) error {
+	err := tokenRevocationDB.RevokeAllForUser(userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return refreshTokenDB.RevokeAllForUser(userID)
+}
+
+func newUserOutput(u *entity.User) dto.UserOutput {
+	return dto.UserOutput{
+		ID:    u.ID,
+		Name:  u.Name,
+		Email: u.Email,
+		Role:  string(u.Role),
+	}
+}
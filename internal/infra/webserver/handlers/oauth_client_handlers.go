@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+	utils "app/pkg/entity"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type OAuthClientHandler struct {
+	ClientDB          database.OAuthClientInterface
+	TokenRevocationDB database.TokenRevocationInterface
+}
+
+func NewOAuthClientHandler(
+	ClientDB database.OAuthClientInterface,
+	TokenRevocationDB database.TokenRevocationInterface,
+) *OAuthClientHandler {
+	return &OAuthClientHandler{
+		ClientDB,
+		TokenRevocationDB,
+	}
+}
+
+// CreateClient godoc
+// @Summary      Register OAuth client
+// @Description  Register a client for the client credentials grant. The secret is only returned once.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.CreateOAuthClientInput  true  "client name and allowed scopes"
+// @Success      201  {object}  dto.OAuthClientSecretOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/oauth-clients [post]
+// @Security ApiKeyAuth
+func (h *OAuthClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var input dto.CreateOAuthClientInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	adminID, _ := currentUser(r)
+
+	scopes := make([]entity.Scope, 0, len(input.Scopes))
+	for _, s := range input.Scopes {
+		scopes = append(scopes, entity.Scope(s))
+	}
+
+	client, secret, err := entity.NewOAuthClient(input.Name, scopes, adminID)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.ClientDB.Create(client)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Client")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.OAuthClientSecretOutput{
+		OAuthClientOutput: newOAuthClientOutput(client),
+		ClientSecret:      secret,
+	})
+}
+
+// ListClients godoc
+// @Summary      List OAuth clients
+// @Description  List every registered OAuth client
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   dto.OAuthClientOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/oauth-clients [get]
+// @Security ApiKeyAuth
+func (h *OAuthClientHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.ClientDB.FindAll()
+	if err != nil {
+		writeRepositoryError(w, r, err, "Client")
+		return
+	}
+
+	output := make([]dto.OAuthClientOutput, 0, len(clients))
+	for i := range clients {
+		output = append(output, newOAuthClientOutput(&clients[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// GetClient godoc
+// @Summary      Get OAuth client
+// @Description  Get a registered OAuth client
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "client ID" Format(uuid)
+// @Success      200  {object}  dto.OAuthClientOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Router       /api/v1/admin/oauth-clients/{id} [get]
+// @Security ApiKeyAuth
+func (h *OAuthClientHandler) GetClient(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.findClient(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newOAuthClientOutput(client))
+}
+
+// RotateClientSecret godoc
+// @Summary      Rotate OAuth client secret
+// @Description  Replace the secret of a client. The old secret stops working at once and every token issued to the client is revoked.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "client ID" Format(uuid)
+// @Success      200  {object}  dto.OAuthClientSecretOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/oauth-clients/{id}/secret [post]
+// @Security ApiKeyAuth
+func (h *OAuthClientHandler) RotateClientSecret(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.findClient(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := client.RotateSecret()
+	if err == nil {
+		err = h.ClientDB.Update(client)
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error rotating client secret")
+		return
+	}
+
+	// A secret is rotated when it may have leaked, so the tokens it got go too.
+	// The rotation is only confirmed once they are revoked.
+	err = h.TokenRevocationDB.RevokeAllForUser(client.ID, time.Now())
+	if err != nil {
+		writeRepositoryError(w, r, err, "Client tokens")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.OAuthClientSecretOutput{
+		OAuthClientOutput: newOAuthClientOutput(client),
+		ClientSecret:      secret,
+	})
+}
+
+// DeleteClient godoc
+// @Summary      Delete OAuth client
+// @Description  Delete a client and revoke every token issued to it
+// @Tags         admin
+// @Param        id   path      string  true  "client ID" Format(uuid)
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/oauth-clients/{id} [delete]
+// @Security ApiKeyAuth
+func (h *OAuthClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	err := h.ClientDB.Delete(id)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Client")
+		return
+	}
+
+	// Client tokens use the client ID as subject, so the per user revocation
+	// covers them too.
+	err = h.TokenRevocationDB.RevokeAllForUser(id, time.Now())
+	if err != nil {
+		writeRepositoryError(w, r, err, "Client tokens")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *OAuthClientHandler) findClient(w http.ResponseWriter, r *http.Request) (*entity.OAuthClient, bool) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return nil, false
+	}
+
+	client, err := h.ClientDB.FindByID(id)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Client")
+		return nil, false
+	}
+
+	return client, true
+}
+
+func newOAuthClientOutput(c *entity.OAuthClient) dto.OAuthClientOutput {
+	scopes := make([]string, 0)
+	for _, s := range c.ScopeList() {
+		scopes = append(scopes, string(s))
+	}
+
+	return dto.OAuthClientOutput{
+		ClientID:  c.ID,
+		Name:      c.Name,
+		Scopes:    scopes,
+		CreatedBy: c.CreatedBy,
+		CreatedAt: c.CreatedAt,
+	}
+}
@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+	"app/internal/infra/webserver/handlers"
+	"app/internal/infra/webserver/middlewares"
+	"app/pkg/jwtkeys"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type oauthClientServer struct {
+	handler  http.Handler
+	clientDB *database.OAuthClient
+}
+
+func newOAuthClientServer(t *testing.T) *oauthClientServer {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.OAuthClient{}, &entity.RevokedToken{}, &entity.UserTokenRevocation{})
+
+	// A second connection would open another, empty, in-memory database.
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	key, err := jwtkeys.NewSecretKey("HS256", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := jwtkeys.NewKeyRing(key, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientDB := database.NewOAuthClient(db)
+	tokenRevocationDB := database.NewTokenRevocation(db)
+	tokens := handlers.NewTokenIssuer(database.NewRefreshToken(db), keys, 3600, 3600, 300, 3600)
+	oauthHandler := handlers.NewOAuthHandler(clientDB, tokenRevocationDB, tokens)
+	oauthClientHandler := handlers.NewOAuthClientHandler(clientDB, tokenRevocationDB)
+
+	r := chi.NewRouter()
+	r.Post("/oauth/token", oauthHandler.Token)
+	r.Post("/oauth-clients/{id}/secret", oauthClientHandler.RotateClientSecret)
+	r.Delete("/oauth-clients/{id}", oauthClientHandler.DeleteClient)
+	r.With(middlewares.Authenticate(keys, tokenRevocationDB, database.NewAPIKey(db), database.NewUser(db))).
+		Get("/products", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+	return &oauthClientServer{r, clientDB}
+}
+
+func (s *oauthClientServer) serve(r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, r)
+	return w
+}
+
+func (s *oauthClientServer) createClient(t *testing.T) (*entity.OAuthClient, string) {
+	client, secret, err := entity.NewOAuthClient("Client", []entity.Scope{entity.ScopeProductsRead}, "admin-id")
+	assert.Nil(t, err)
+	assert.Nil(t, s.clientDB.Create(client))
+	return client, secret
+}
+
+func (s *oauthClientServer) clientToken(t *testing.T, clientID, secret string) string {
+	form := url.Values{"grant_type": {"client_credentials"}, "client_id": {clientID}, "client_secret": {secret}}
+	r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := s.serve(r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var output struct {
+		AccessToken string `json:"access_token"`
+	}
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&output))
+	return output.AccessToken
+}
+
+func (s *oauthClientServer) getProducts(token string) int {
+	r := httptest.NewRequest(http.MethodGet, "/products", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return s.serve(r).Code
+}
+
+func TestRotateClientSecretRevokesClientTokens(t *testing.T) {
+	s := newOAuthClientServer(t)
+	client, secret := s.createClient(t)
+
+	token := s.clientToken(t, client.ID, secret)
+	assert.Equal(t, http.StatusOK, s.getProducts(token))
+
+	w := s.serve(httptest.NewRequest(http.MethodPost, "/oauth-clients/"+client.ID+"/secret", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, s.getProducts(token))
+}
+
+func TestDeleteClientRevokesClientTokens(t *testing.T) {
+	s := newOAuthClientServer(t)
+	client, secret := s.createClient(t)
+
+	token := s.clientToken(t, client.ID, secret)
+	assert.Equal(t, http.StatusOK, s.getProducts(token))
+
+	w := s.serve(httptest.NewRequest(http.MethodDelete, "/oauth-clients/"+client.ID, nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	assert.Equal(t, http.StatusUnauthorized, s.getProducts(token))
+}
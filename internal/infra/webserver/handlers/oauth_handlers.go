@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+)
+
+// OAuthHandler implements the client credentials grant of RFC 6749 and token
+// introspection of RFC 7662. Both take form encoded bodies and answer with
+// the error format of RFC 6749 instead of Error.
+type OAuthHandler struct {
+	ClientDB          database.OAuthClientInterface
+	TokenRevocationDB database.TokenRevocationInterface
+	Tokens            *TokenIssuer
+}
+
+func NewOAuthHandler(
+	ClientDB database.OAuthClientInterface,
+	TokenRevocationDB database.TokenRevocationInterface,
+	Tokens *TokenIssuer,
+) *OAuthHandler {
+	return &OAuthHandler{
+		ClientDB,
+		TokenRevocationDB,
+		Tokens,
+	}
+}
+
+// Token godoc
+// @Summary      OAuth2 token
+// @Description  Issue an access token to a registered client with the client credentials grant. The client authenticates with HTTP Basic or the client_id and client_secret fields.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "client_credentials"
+// @Param        scope          formData  string  false  "space separated scopes, all scopes of the client when empty"
+// @Param        client_id      formData  string  false  "client ID, when not using HTTP Basic"
+// @Param        client_secret  formData  string  false  "client secret, when not using HTTP Basic"
+// @Success      200  {object}  dto.OAuthTokenOutput
+// @Failure      400  {object}  dto.OAuthErrorOutput
+// @Failure      401  {object}  dto.OAuthErrorOutput
+// @Failure      500  {object}  dto.OAuthErrorOutput
+// @Router       /oauth/token [post]
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Invalid form body")
+		return
+	}
+
+	grantType := r.PostForm.Get("grant_type")
+	if grantType == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "grant_type is required")
+		return
+	}
+
+	client, ok := h.authenticateClient(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	if grantType != "client_credentials" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Only client_credentials is supported")
+		return
+	}
+
+	scopes, err := client.GrantScopes(r.PostForm.Get("scope"))
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "Scope is not allowed for this client")
+		return
+	}
+
+	output, err := h.Tokens.IssueClientToken(client, scopes)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "Error generating token")
+		return
+	}
+
+	setNoStore(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// Introspect godoc
+// @Summary      OAuth2 token introspection
+// @Description  Tell a registered client whether an access token issued by the API is active, as described in RFC 7662
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token          formData  string  true   "access token"
+// @Param        client_id      formData  string  false  "client ID, when not using HTTP Basic"
+// @Param        client_secret  formData  string  false  "client secret, when not using HTTP Basic"
+// @Success      200  {object}  dto.IntrospectionOutput
+// @Failure      400  {object}  dto.OAuthErrorOutput
+// @Failure      401  {object}  dto.OAuthErrorOutput
+// @Failure      500  {object}  dto.OAuthErrorOutput
+// @Router       /oauth/introspect [post]
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Invalid form body")
+		return
+	}
+
+	if _, ok := h.authenticateClient(r); !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	tokenString := r.PostForm.Get("token")
+	if tokenString == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	output := dto.IntrospectionOutput{Active: false}
+
+	token, err := h.Tokens.Jwt.Verify(tokenString)
+	if err == nil {
+		claims, _ := token.AsMap(r.Context())
+		typ, _ := claims["typ"].(string)
+
+		if typ == entity.TokenTypeAccess || typ == entity.TokenTypeClient {
+			issuedAt := entity.TokenIssuedAt(token.IssuedAt(), claims)
+			revoked, err := h.TokenRevocationDB.IsRevoked(token.JwtID(), token.Subject(), issuedAt)
+			if err != nil {
+				writeOAuthError(w, http.StatusInternalServerError, "server_error", "Error checking token")
+				return
+			}
+
+			if !revoked {
+				scope, _ := claims["scope"].(string)
+				clientID, _ := claims["client_id"].(string)
+
+				output = dto.IntrospectionOutput{
+					Active:    true,
+					Scope:     scope,
+					ClientID:  clientID,
+					Sub:       token.Subject(),
+					TokenType: "Bearer",
+					Exp:       token.Expiration().Unix(),
+					Iat:       token.IssuedAt().Unix(),
+					Jti:       token.JwtID(),
+				}
+			}
+		}
+	}
+
+	setNoStore(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// authenticateClient checks the client credentials of HTTP Basic, whose
+// values are form encoded as RFC 6749 requires, or of the form body.
+func (h *OAuthHandler) authenticateClient(r *http.Request) (*entity.OAuthClient, bool) {
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		var errID, errSecret error
+		clientID, errID = url.QueryUnescape(clientID)
+		secret, errSecret = url.QueryUnescape(secret)
+		if errors.Join(errID, errSecret) != nil {
+			return nil, false
+		}
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	if clientID == "" || secret == "" {
+		return nil, false
+	}
+
+	client, err := h.ClientDB.FindByID(clientID)
+	if err != nil || !client.ValidateSecret(secret) {
+		return nil, false
+	}
+
+	return client, true
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	setNoStore(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(dto.OAuthErrorOutput{Error: code, ErrorDescription: description})
+}
+
+func setNoStore(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+}
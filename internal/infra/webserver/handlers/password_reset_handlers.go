@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+	"app/internal/infra/mail"
+)
+
+type PasswordResetHandler struct {
+	UserDB               database.UserInterface
+	PasswordResetTokenDB database.PasswordResetTokenInterface
+	RefreshTokenDB       database.RefreshTokenInterface
+	TokenRevocationDB    database.TokenRevocationInterface
+	Mailer               mail.Mailer
+	LoginThrottler       *LoginThrottler
+	ExpiresIn            int
+}
+
+func NewPasswordResetHandler(
+	UserDB database.UserInterface,
+	PasswordResetTokenDB database.PasswordResetTokenInterface,
+	RefreshTokenDB database.RefreshTokenInterface,
+	TokenRevocationDB database.TokenRevocationInterface,
+	Mailer mail.Mailer,
+	LoginThrottler *LoginThrottler,
+	ExpiresIn int,
+) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		UserDB,
+		PasswordResetTokenDB,
+		RefreshTokenDB,
+		TokenRevocationDB,
+		Mailer,
+		LoginThrottler,
+		ExpiresIn,
+	}
+}
+
+// RequestPasswordReset godoc
+// @Summary      Request a password reset
+// @Description  Email a single use password reset token. The response is the same whether the email exists or not. Requests count against the login attempts of the client IP.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.PasswordResetRequestInput  true  "account email"
+// @Success      202  {object}  dto.MessageOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/password-reset/request [post]
+func (h *PasswordResetHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	// Every request mails someone, so it shares the IP window of logins to
+	// keep the endpoint from flooding a mailbox or the mailer.
+	wait, err := h.LoginThrottler.CheckIP(clientIP(r))
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if wait > 0 {
+		setRetryAfter(w, wait)
+		writeProblem(w, r, http.StatusTooManyRequests, "Too many requests")
+		return
+	}
+
+	var input dto.PasswordResetRequestInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	if input.Email == "" {
+		writeRequiredField(w, r, "email")
+		return
+	}
+
+	// The token and the mail are left out of the response time, or a
+	// registered email would answer measurably slower than an unknown one.
+	if u, err := h.UserDB.FindByEmail(input.Email); err == nil {
+		go func() {
+			if err := h.SendPasswordReset(u); err != nil {
+				log.Printf("password reset for user %s: %v", u.ID, err)
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dto.MessageOutput{
+		Message: "If the email is registered, password reset instructions have been sent",
+	})
+}
+
+// ConfirmPasswordReset godoc
+// @Summary      Confirm a password reset
+// @Description  Set a new password using a password reset token and end all sessions of the user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.PasswordResetConfirmInput  true  "reset token and new password"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/password-reset/confirm [post]
+func (h *PasswordResetHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var input dto.PasswordResetConfirmInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	if input.Token == "" {
+		writeRequiredField(w, r, "token")
+		return
+	}
+
+	if input.NewPassword == "" {
+		writeRequiredField(w, r, "new_password")
+		return
+	}
+
+	token, err := h.PasswordResetTokenDB.FindByHash(entity.HashToken(input.Token))
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "Token")
+		return
+	}
+	if err != nil || token.IsUsed() || token.IsExpired() {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	u, err := h.UserDB.FindByID(token.UserID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	err = u.ChangePassword(input.NewPassword)
+	if err != nil {
+		writeValidationProblem(w, r, err, newPasswordField)
+		return
+	}
+
+	err = h.PasswordResetTokenDB.Redeem(token, u)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "Token")
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	err = revokeSessions(h.TokenRevocationDB, h.RefreshTokenDB, u.ID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendPasswordReset emails a new single use reset token to the user.
+func (h *PasswordResetHandler) SendPasswordReset(u *entity.User) error {
+	token, plain, err := entity.NewPasswordResetToken(u.ID, time.Second*time.Duration(h.ExpiresIn))
+	if err != nil {
+		return err
+	}
+
+	err = h.PasswordResetTokenDB.Create(token)
+	if err != nil {
+		return err
+	}
+
+	return h.Mailer.Send(mail.Message{
+		To:      u.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nUse the token below to choose a new password. It expires in %d minutes and can only be used once.\n\n%s\n\nIf you did not ask for a password reset you can ignore this email.\n",
+			u.Name,
+			h.ExpiresIn/60,
+			plain,
+		),
+	})
+}
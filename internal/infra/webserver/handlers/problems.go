@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"app/internal/entity"
+	"app/pkg/problem"
+)
+
+// writeProblem answers with an application/problem+json body of the default
+// type, detail being a message safe to show to the client.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	problem.Write(w, r, status, detail)
+}
+
+// newPasswordField names the password of an entity after the request field it
+// comes from when changing it.
+var newPasswordField = map[string]string{"password": "new_password"}
+
+// writeValidationProblem answers 400 with every field the entity rejected,
+// renamed through fieldNames when the request calls them differently. Errors
+// without field details are sent as a plain 400 problem.
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, err error, fieldNames map[string]string) {
+	fields := entity.FieldErrors(err)
+	if len(fields) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errs := make([]problem.FieldError, 0, len(fields))
+	for _, f := range fields {
+		field := f.Field
+		if name, ok := fieldNames[field]; ok {
+			field = name
+		}
+		errs = append(errs, problem.FieldError{Field: field, Rule: f.Rule, Message: f.Error()})
+	}
+
+	problem.Validation(r, err.Error(), errs).Write(w)
+}
+
+// writeRequiredField answers 400 for a request missing a field the handler
+// needs before any entity can validate it.
+func writeRequiredField(w http.ResponseWriter, r *http.Request, field string) {
+	message := field + " is required"
+	problem.Validation(r, message, []problem.FieldError{{Field: field, Rule: "required", Message: message}}).Write(w)
+}
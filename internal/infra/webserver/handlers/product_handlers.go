@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
+	"mime"
 	"net/http"
 	"strconv"
 
@@ -9,17 +12,21 @@ import (
 	"app/internal/entity"
 	"app/internal/infra/database"
 	utils "app/pkg/entity"
+	"app/pkg/jsonpatch"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth"
 )
 
 type ProductHandler struct {
 	ProductDB database.ProductInterface
+	Audit     *Auditor
 }
 
-func NeProductHandler(db database.ProductInterface) *ProductHandler {
+func NeProductHandler(db database.ProductInterface, audit *Auditor) *ProductHandler {
 	return &ProductHandler{
 		ProductDB: db,
+		Audit:     audit,
 	}
 }
 
@@ -31,156 +38,653 @@ func NeProductHandler(db database.ProductInterface) *ProductHandler {
 // @Produce      json
 // @Param        request     body      dto.CreateProductInput  true  "product request"
 // @Success      201
-// @Failure      500         {object}  Error
+// @Failure      400         {object}  problem.Problem
+// @Failure      413         {object}  problem.Problem
+// @Failure      415         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
 // @Router       /api/v1/products [post]
 // @Security ApiKeyAuth
+// @Security XApiKeyAuth
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var product dto.CreateProductInput
-	err := json.NewDecoder(r.Body).Decode(&product)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		err := Error{Message: "Invalid request"}
-		json.NewEncoder(w).Encode(err)
+	if !decodeJSON(w, r, &product) {
 		return
 	}
 
 	p, err := entity.NewProduct(product.Name, product.Price)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		err := Error{Message: "Invalid product data"}
-		json.NewEncoder(w).Encode(err)
+		writeValidationProblem(w, r, err, nil)
 		return
 	}
 
-	err = h.ProductDB.Create(p)
+	p.OwnerID, _ = currentUser(r)
+
+	err = h.ProductDB.Create(p, p.OwnerID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		err := Error{Message: "Internal Server Error"}
-		json.NewEncoder(w).Encode(err)
+		writeRepositoryError(w, r, err, "Product")
 		return
 	}
 
+	h.auditChange(r, entity.AuditProductCreated, p.ID, nil, p)
+
 	w.WriteHeader(http.StatusCreated)
 }
 
 // GetProduct godoc
 // @Summary      Get a product
-// @Description  Get a product
+// @Description  Get a product. Its ETag header is the version of the product, answered with 304 when given in If-None-Match.
 // @Tags         products
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true  "product ID" Format(uuid)
-// @Success      200  {object}  entity.Product
-// @Failure      400  {object}  Error
-// @Failure      404  {object}  Error
-// @Failure      500  {object}  Error
+// @Param        id             path      string  true   "product ID" Format(uuid)
+// @Param        If-None-Match  header    string  false  "ETag of a cached copy"
+// @Success      200  {object}  dto.ProductOutput
+// @Success      304
+// @Failure      400  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
 // @Router       /api/v1/products/{id} [get]
 // @Security ApiKeyAuth
+// @Security XApiKeyAuth
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if !utils.IsUUID(id) {
-		w.WriteHeader(http.StatusBadRequest)
-		err := Error{Message: "Invalid UUID"}
-		json.NewEncoder(w).Encode(err)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
 		return
 	}
 
 	product, err := h.ProductDB.FindById(id)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		err := Error{Message: "Product not found"}
-		json.NewEncoder(w).Encode(err)
+		writeRepositoryError(w, r, err, "Product")
+		return
+	}
+
+	output := newProductOutput(product)
+	w.Header().Set("ETag", output.ETag)
+
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, output.ETag, true) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(product)
+	json.NewEncoder(w).Encode(output)
 }
 
 // UpdateProduct godoc
 // @Summary      Update a product
-// @Description  Update a product
+// @Description  Replace the name and price of a product
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id        	path      string                  true  "product ID" Format(uuid)
+// @Param        If-Match    header    string                  false  "ETag the product must still have"
 // @Param        request     body      dto.UpdateProductInput  true  "product request"
-// @Success      200
-// @Failure      400			{object}  Error
-// @Failure      404			{object}  Error
-// @Failure      500      {object}  Error
+// @Success      200      {object}  dto.ProductOutput
+// @Failure      400			{object}  problem.Problem
+// @Failure      403			{object}  problem.Problem
+// @Failure      404			{object}  problem.Problem
+// @Failure      409			{object}  problem.Problem
+// @Failure      412			{object}  problem.Problem
+// @Failure      413			{object}  problem.Problem
+// @Failure      415			{object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
 // @Router       /api/v1/products/{id} [put]
 // @Security ApiKeyAuth
+// @Security XApiKeyAuth
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if !utils.IsUUID(id) {
-		w.WriteHeader(http.StatusBadRequest)
-		err := Error{Message: "Invalid UUID"}
-		json.NewEncoder(w).Encode(err)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
 		return
 	}
 
 	var product dto.UpdateProductInput
-	err := json.NewDecoder(r.Body).Decode(&product)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		err := Error{Message: "Invalid request"}
-		json.NewEncoder(w).Encode(err)
+	if !decodeJSON(w, r, &product) {
 		return
 	}
 
-	p, err := entity.NewProduct(product.Name, product.Price)
+	existing, ok := h.findModifiableProduct(w, r, id, "modify")
+	if !ok {
+		return
+	}
+
+	h.saveProductChange(w, r, existing, product)
+}
+
+// PatchProduct godoc
+// @Summary      Partially update a product
+// @Description  Change only some fields of a product, with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) of its name and price
+// @Tags         products
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id        	path      string                  true  "product ID" Format(uuid)
+// @Param        If-Match    header    string                  false  "ETag the product must still have"
+// @Param        request     body      dto.UpdateProductInput  true  "fields to change, or a list of JSON Patch operations"
+// @Success      200      {object}  dto.ProductOutput
+// @Failure      400			{object}  problem.Problem
+// @Failure      403			{object}  problem.Problem
+// @Failure      404			{object}  problem.Problem
+// @Failure      409			{object}  problem.Problem
+// @Failure      412			{object}  problem.Problem
+// @Failure      413			{object}  problem.Problem
+// @Failure      415			{object}  problem.Problem
+// @Failure      422			{object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
+// @Router       /api/v1/products/{id} [patch]
+// @Security ApiKeyAuth
+// @Security XApiKeyAuth
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Patch", jsonpatch.MergePatchType+", "+jsonpatch.JSONPatchType)
+
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	var patch json.RawMessage
+	err := decodeJSONBody(r, &patch, jsonpatch.MergePatchType, jsonpatch.JSONPatchType)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		err := Error{Message: "Invalid product data"}
-		json.NewEncoder(w).Encode(err)
+		writeBodyError(w, r, err)
 		return
 	}
 
-	p.ID = id
+	var ops []jsonpatch.Operation
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == jsonpatch.JSONPatchType {
+		if err := unmarshalStrict(patch, &ops); err != nil {
+			writeBodyError(w, r, err)
+			return
+		}
+	}
+
+	existing, ok := h.findModifiableProduct(w, r, id, "modify")
+	if !ok {
+		return
+	}
 
-	err = h.ProductDB.Update(p)
+	// Patches apply to the fields a PUT would replace, so anything else, like
+	// the ID or the owner, is refused as an unknown field.
+	doc, err := json.Marshal(dto.UpdateProductInput{Name: existing.Name, Price: existing.Price})
+	if err == nil {
+		if ops != nil {
+			doc, err = jsonpatch.Apply(doc, ops)
+		} else {
+			doc, err = jsonpatch.MergePatch(doc, patch)
+		}
+	}
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		writeProblem(w, r, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, jsonpatch.ErrInvalidOperation),
+		errors.Is(err, jsonpatch.ErrInvalidPointer),
+		errors.Is(err, jsonpatch.ErrPathNotFound):
+		writeProblem(w, r, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		log.Printf("patch of product %s: %v", id, err)
+		writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	var product dto.UpdateProductInput
+	if err := unmarshalStrict(doc, &product); err != nil {
+		writeBodyError(w, r, err)
+		return
+	}
+
+	h.saveProductChange(w, r, existing, product)
+}
+
+// findModifiableProduct loads a product the current user may change and whose
+// version matches If-Match, when given, answering 404, 403 or 412 otherwise.
+// action names the change in the 403 message.
+func (h *ProductHandler) findModifiableProduct(w http.ResponseWriter, r *http.Request, id, action string) (*entity.Product, bool) {
+	existing, err := h.ProductDB.FindById(id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		err := Error{Message: "Internal Server Error"}
-		json.NewEncoder(w).Encode(err)
+		writeRepositoryError(w, r, err, "Product")
+		return nil, false
+	}
+
+	return existing, checkModifiable(w, r, existing, action)
+}
+
+// checkModifiable answers 403 unless the current user may change the product,
+// and 412 when If-Match is given and does not match its version.
+func checkModifiable(w http.ResponseWriter, r *http.Request, existing *entity.Product, action string) bool {
+	if !canModifyProduct(r, existing) {
+		writeProblem(w, r, http.StatusForbidden, "You are not allowed to "+action+" this product")
+		return false
+	}
+
+	etag := versionETag(existing.Version)
+	if match := r.Header.Get("If-Match"); match != "" && !etagMatches(match, etag, false) {
+		w.Header().Set("ETag", etag)
+		writeProblem(w, r, http.StatusPreconditionFailed, "Product has changed, its current ETag is "+etag)
+		return false
+	}
+
+	return true
+}
+
+// writeProductWriteError answers a failed product write. Losing the race to
+// another writer after If-Match was checked still fails the precondition.
+func writeProductWriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, database.ErrVersionConflict) && r.Header.Get("If-Match") != "" {
+		writeProblem(w, r, http.StatusPreconditionFailed, "Product has changed")
+		return
+	}
+
+	writeRepositoryError(w, r, err, "Product")
+}
+
+// saveProductChange stores the input fields on a copy of the existing product,
+// keeping its ID, owner and creation time, and answers with the result.
+func (h *ProductHandler) saveProductChange(w http.ResponseWriter, r *http.Request, existing *entity.Product, input dto.UpdateProductInput) {
+	p := *existing
+	p.Name = input.Name
+	p.Price = input.Price
+
+	if err := p.Validate(); err != nil {
+		writeValidationProblem(w, r, err, nil)
 		return
 	}
 
+	actorID, _ := currentUser(r)
+	if err := h.ProductDB.Update(&p, actorID); err != nil {
+		writeProductWriteError(w, r, err)
+		return
+	}
+
+	h.auditChange(r, entity.AuditProductUpdated, p.ID, existing, &p)
+
+	output := newProductOutput(&p)
+	w.Header().Set("ETag", output.ETag)
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
 }
 
 // DeleteProduct godoc
 // @Summary      Delete a product
-// @Description  Delete a product
+// @Description  Move a product to the trash, from where it can be restored until it is purged
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id        path      string                  true  "product ID" Format(uuid)
+// @Param        If-Match  header    string                  false  "ETag the product must still have"
 // @Success      200
-// @Failure      404 			{object}  Error
-// @Failure      500      {object}  Error
+// @Failure      403 			{object}  problem.Problem
+// @Failure      404 			{object}  problem.Problem
+// @Failure      409 			{object}  problem.Problem
+// @Failure      412 			{object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
 // @Router       /api/v1/products/{id} [delete]
 // @Security ApiKeyAuth
+// @Security XApiKeyAuth
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if !utils.IsUUID(id) {
-		w.WriteHeader(http.StatusBadRequest)
-		err := Error{Message: "Invalid UUID"}
-		json.NewEncoder(w).Encode(err)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	existing, ok := h.findModifiableProduct(w, r, id, "delete")
+	if !ok {
+		return
+	}
+
+	actorID, _ := currentUser(r)
+	err := h.ProductDB.Delete(existing, actorID)
+	if err != nil {
+		writeProductWriteError(w, r, err)
+		return
+	}
+
+	h.auditChange(r, entity.AuditProductDeleted, id, existing, nil)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// TrashProducts godoc
+// @Summary      List deleted products
+// @Description  List the products in the trash, most recently deleted first. Admins see every deleted product, other users only their own.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        page      query     string  false  "page number"
+// @Param        limit     query     string  false  "limit"
+// @Param        sort      query     string  false  "order of deletion" Enums(asc, desc)
+// @Success      200       {array}   dto.ProductOutput
+// @Failure      500       {object}  problem.Problem
+// @Router       /api/v1/products/trash [get]
+// @Security ApiKeyAuth
+// @Security XApiKeyAuth
+func (h *ProductHandler) TrashProducts(w http.ResponseWriter, r *http.Request) {
+	page, limit, sort := pagination(r)
+
+	var products []entity.Product
+	var err error
+
+	userID, role := currentUser(r)
+	if role.Includes(entity.RoleAdmin) {
+		products, err = h.ProductDB.FindAllDeleted(page, limit, sort)
+	} else {
+		products, err = h.ProductDB.FindAllDeletedByOwner(userID, page, limit, sort)
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "Product")
+		return
+	}
+
+	writeProductList(w, products)
+}
+
+// RestoreProduct godoc
+// @Summary      Restore a deleted product
+// @Description  Take a product out of the trash
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true   "product ID" Format(uuid)
+// @Param        If-Match  header    string  false  "ETag the deleted product must still have"
+// @Success      200       {object}  dto.ProductOutput
+// @Failure      400       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      409       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /api/v1/products/{id}/restore [post]
+// @Security ApiKeyAuth
+// @Security XApiKeyAuth
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	existing, err := h.ProductDB.FindDeletedById(id)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Deleted product")
+		return
+	}
+
+	if !checkModifiable(w, r, existing, "restore") {
+		return
+	}
+
+	p := *existing
+	actorID, _ := currentUser(r)
+	if err := h.ProductDB.Restore(&p, actorID); err != nil {
+		writeProductWriteError(w, r, err)
+		return
+	}
+
+	h.auditChange(r, entity.AuditProductRestored, id, existing, &p)
+
+	output := newProductOutput(&p)
+	w.Header().Set("ETag", output.ETag)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// PurgeProduct godoc
+// @Summary      Purge a deleted product
+// @Description  Permanently delete a product from the trash. Only admins may purge products.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true  "product ID" Format(uuid)
+// @Success      200
+// @Failure      400       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /api/v1/products/trash/{id} [delete]
+// @Security ApiKeyAuth
+// @Security XApiKeyAuth
+func (h *ProductHandler) PurgeProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	existing, err := h.ProductDB.FindDeletedById(id)
+	if err == nil {
+		err = h.ProductDB.Purge(id)
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "Deleted product")
+		return
+	}
+
+	h.auditChange(r, entity.AuditProductPurged, id, existing, nil)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListProductRevisions godoc
+// @Summary      List product revisions
+// @Description  List the revisions of a product, oldest first. Every change records one, numbered after the version it gave the product. The revisions of a deleted product are only listed to those who may restore it.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true   "product ID" Format(uuid)
+// @Param        page      query     string  false  "page number"
+// @Param        limit     query     string  false  "limit"
+// @Success      200       {array}   entity.ProductRevision
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /api/v1/products/{id}/revisions [get]
+// @Security ApiKeyAuth
+// @Security XApiKeyAuth
+func (h *ProductHandler) ListProductRevisions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	if !h.checkRevisionsReadable(w, r, id) {
+		return
+	}
+
+	page, limit, _ := pagination(r)
+	revisions, err := h.ProductDB.FindRevisions(id, page, limit)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Revision")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// GetProductRevision godoc
+// @Summary      Get a product revision
+// @Description  Get the product as it was at a version
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true  "product ID" Format(uuid)
+// @Param        n         path      int     true  "revision number"
+// @Success      200       {object}  entity.ProductRevision
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /api/v1/products/{id}/revisions/{n} [get]
+// @Security ApiKeyAuth
+// @Security XApiKeyAuth
+func (h *ProductHandler) GetProductRevision(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	n, ok := revisionNumber(chi.URLParam(r, "n"))
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid revision number")
+		return
+	}
+
+	if !h.checkRevisionsReadable(w, r, id) {
+		return
+	}
+
+	revision, err := h.ProductDB.FindRevision(id, n)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Revision")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revision)
+}
+
+// DiffProductRevisions godoc
+// @Summary      Compare product revisions
+// @Description  List the fields of a product that differ between two of its revisions
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true  "product ID" Format(uuid)
+// @Param        from      query     int     true  "revision number to compare from"
+// @Param        to        query     int     true  "revision number to compare to"
+// @Success      200       {object}  dto.ProductRevisionDiff
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /api/v1/products/{id}/revisions/diff [get]
+// @Security ApiKeyAuth
+// @Security XApiKeyAuth
+func (h *ProductHandler) DiffProductRevisions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	from, ok := revisionNumber(r.URL.Query().Get("from"))
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid revision number in from")
+		return
+	}
+	to, ok := revisionNumber(r.URL.Query().Get("to"))
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid revision number in to")
+		return
+	}
+
+	if !h.checkRevisionsReadable(w, r, id) {
+		return
+	}
+
+	before, err := h.ProductDB.FindRevision(id, from)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Revision")
+		return
+	}
+	after, err := h.ProductDB.FindRevision(id, to)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Revision")
 		return
 	}
 
-	err := h.ProductDB.Delete(id)
+	changes, err := entity.DiffProducts(&before.Snapshot, &after.Snapshot)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		err := Error{Message: "Internal Server Error"}
-		json.NewEncoder(w).Encode(err)
+		log.Printf("diff of product %s revisions %d and %d: %v", id, from, to, err)
+		writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ProductRevisionDiff{ProductID: id, From: from, To: to, Changes: changes})
+}
+
+// RevertProduct godoc
+// @Summary      Revert a product
+// @Description  Set the name and price of a product back to those of one of its revisions. The revert is a change like any other, recorded as a new revision.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true   "product ID" Format(uuid)
+// @Param        n         path      int     true   "revision number"
+// @Param        If-Match  header    string  false  "ETag the product must still have"
+// @Success      200       {object}  dto.ProductOutput
+// @Failure      400       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      409       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /api/v1/products/{id}/revert/{n} [post]
+// @Security ApiKeyAuth
+// @Security XApiKeyAuth
+func (h *ProductHandler) RevertProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !utils.IsUUID(id) {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	n, ok := revisionNumber(chi.URLParam(r, "n"))
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid revision number")
+		return
+	}
+
+	existing, ok := h.findModifiableProduct(w, r, id, "modify")
+	if !ok {
+		return
+	}
+
+	revision, err := h.ProductDB.FindRevision(id, n)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Revision")
+		return
+	}
+
+	h.saveProductChange(w, r, existing, dto.UpdateProductInput{
+		Name:  revision.Snapshot.Name,
+		Price: revision.Snapshot.Price,
+	})
+}
+
+// checkRevisionsReadable answers 404 unless the product has revisions the
+// current user may read: those of a deleted product are only shown to whoever
+// could restore it, like the trash.
+func (h *ProductHandler) checkRevisionsReadable(w http.ResponseWriter, r *http.Request, id string) bool {
+	latest, err := h.ProductDB.FindLatestRevision(id)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Product")
+		return false
+	}
+
+	if latest.Snapshot.IsDeleted() && !canModifyProduct(r, &latest.Snapshot) {
+		writeProblem(w, r, http.StatusNotFound, "Product not found")
+		return false
+	}
+
+	return true
+}
+
+// revisionNumber parses a revision number, which starts at 1.
+func revisionNumber(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n > 0
 }
 
 // List Products godoc
@@ -191,35 +695,100 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        page      query     string  false  "page number"
 // @Param        limit     query     string  false  "limit"
-// @Success      200       {array}   entity.Product
-// @Failure      404       {object}  Error
-// @Failure      500       {object}  Error
+// @Param        owner     query     string  false  "only products owned by the current user" Enums(me)
+// @Success      200       {array}   dto.ProductOutput
+// @Failure      404       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
 // @Router       /api/v1/products [get]
 // @Security ApiKeyAuth
+// @Security XApiKeyAuth
 func (h *ProductHandler) FindManyProducts(w http.ResponseWriter, r *http.Request) {
-	pageString := r.URL.Query().Get("page")
-	limitString := r.URL.Query().Get("limit")
-	sort := r.URL.Query().Get("sort")
+	page, limit, sort := pagination(r)
+
+	var products []entity.Product
+	var err error
+
+	switch r.URL.Query().Get("owner") {
+	case "":
+		products, err = h.ProductDB.FindAll(page, limit, sort)
+	case "me":
+		userID, _ := currentUser(r)
+		products, err = h.ProductDB.FindAllByOwner(userID, page, limit, sort)
+	default:
+		writeProblem(w, r, http.StatusBadRequest, "Invalid owner filter")
+		return
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "Product")
+		return
+	}
+
+	writeProductList(w, products)
+}
 
-	page, err := strconv.Atoi(pageString)
+// pagination reads the page, limit and sort query parameters, defaulting to
+// the first page of 10.
+func pagination(r *http.Request) (int, int, string) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
 	if err != nil {
 		page = 1
 	}
 
-	limit, err := strconv.Atoi(limitString)
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
 	if err != nil {
 		limit = 10
 	}
 
-	products, err := h.ProductDB.FindAll(page, limit, sort)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		err := Error{Message: "Internal Server Error"}
-		json.NewEncoder(w).Encode(err)
-		return
+	return page, limit, r.URL.Query().Get("sort")
+}
+
+func writeProductList(w http.ResponseWriter, products []entity.Product) {
+	output := make([]dto.ProductOutput, 0, len(products))
+	for i := range products {
+		output = append(output, newProductOutput(&products[i]))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(output)
+}
+
+func newProductOutput(p *entity.Product) dto.ProductOutput {
+	return dto.ProductOutput{Product: *p, ETag: versionETag(p.Version)}
+}
+
+// currentUser returns the subject and role claims of the authenticated request.
+func currentUser(r *http.Request) (string, entity.Role) {
+	_, claims, _ := jwtauth.FromContext(r.Context())
+
+	sub, _ := claims["sub"].(string)
+	role, _ := claims["role"].(string)
+
+	return sub, entity.Role(role)
+}
+
+// canModifyProduct allows admins to change any product and everyone else only
+// the products they own.
+func canModifyProduct(r *http.Request, p *entity.Product) bool {
+	userID, role := currentUser(r)
+
+	return role.Includes(entity.RoleAdmin) || p.IsOwnedBy(userID)
+}
+
+// auditChange records a change of a product with its state before and after.
+func (h *ProductHandler) auditChange(r *http.Request, action entity.AuditAction, id string, before, after *entity.Product) {
+	event := entity.NewAuditEvent(action, "product", id)
+
+	var beforeSnapshot, afterSnapshot interface{}
+	if before != nil {
+		beforeSnapshot = before
+	}
+	if after != nil {
+		afterSnapshot = after
+	}
+	if err := event.SetSnapshots(beforeSnapshot, afterSnapshot); err != nil {
+		log.Printf("audit snapshot of product %s: %v", id, err)
+	}
+
+	h.Audit.Record(r, event)
 }
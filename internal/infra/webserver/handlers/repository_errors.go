@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"app/internal/infra/database"
+)
+
+// writeRepositoryError answers a failed repository call with the status code
+// of its error kind. resource names the entity in the messages, e.g. "Product".
+func writeRepositoryError(w http.ResponseWriter, r *http.Request, err error, resource string) {
+	status, message := repositoryErrorStatus(err, resource)
+	if status == http.StatusInternalServerError || status == http.StatusServiceUnavailable {
+		log.Printf("%s repository: %v", resource, err)
+	}
+
+	writeProblem(w, r, status, message)
+}
+
+func repositoryErrorStatus(err error, resource string) (int, string) {
+	switch {
+	case errors.Is(err, database.ErrEmailAlreadyExists):
+		return http.StatusConflict, "Email already registered"
+	case errors.Is(err, database.ErrVersionConflict):
+		return http.StatusConflict, resource + " was changed by another request"
+	case errors.Is(err, database.ErrNotFound):
+		return http.StatusNotFound, resource + " not found"
+	case errors.Is(err, database.ErrConflict):
+		return http.StatusConflict, resource + " already exists"
+	case errors.Is(err, database.ErrConstraintViolation):
+		return http.StatusUnprocessableEntity, resource + " breaks a data constraint"
+	case errors.Is(err, database.ErrUnavailable):
+		return http.StatusServiceUnavailable, "Service temporarily unavailable"
+	default:
+		return http.StatusInternalServerError, "Internal Server Error"
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+	utils "app/pkg/entity"
+	"app/pkg/jwtkeys"
+
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+var ErrInvalidMfaToken = errors.New("mfa token is invalid")
+
+// TokenIssuer signs every JWT handed out by the API and keeps track of the
+// refresh tokens that go with them.
+type TokenIssuer struct {
+	RefreshTokenDB        database.RefreshTokenInterface
+	Jwt                   *jwtkeys.KeyRing
+	JwtExpiresIn          int
+	JwtRefreshExpiresIn   int
+	MfaChallengeExpiresIn int
+	ClientExpiresIn       int
+}
+
+func NewTokenIssuer(
+	RefreshTokenDB database.RefreshTokenInterface,
+	Jwt *jwtkeys.KeyRing,
+	JwtExpiresIn int,
+	JwtRefreshExpiresIn int,
+	MfaChallengeExpiresIn int,
+	ClientExpiresIn int,
+) *TokenIssuer {
+	return &TokenIssuer{
+		RefreshTokenDB,
+		Jwt,
+		JwtExpiresIn,
+		JwtRefreshExpiresIn,
+		MfaChallengeExpiresIn,
+		ClientExpiresIn,
+	}
+}
+
+// Issue signs a new access token and stores a new refresh token in the given
+// family. When a previous refresh token is passed it is rotated out.
+func (t *TokenIssuer) Issue(u *entity.User, familyID string, previous *entity.RefreshToken) (*dto.GetJwtOutput, error) {
+	now := time.Now()
+	_, accessToken, err := t.Jwt.Encode(map[string]interface{}{
+		"sub":    u.ID,
+		"typ":    entity.TokenTypeAccess,
+		"role":   string(u.Role),
+		"jti":    utils.NewID().String(),
+		"iat":    now.Unix(),
+		"iat_ms": now.UnixMilli(),
+		"exp":    now.Add(time.Second * time.Duration(t.JwtExpiresIn)).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rt, refreshToken, err := entity.NewRefreshToken(u.ID, familyID, time.Second*time.Duration(t.JwtRefreshExpiresIn))
+	if err != nil {
+		return nil, err
+	}
+
+	if previous != nil {
+		err = t.RefreshTokenDB.Rotate(previous, rt)
+	} else {
+		err = t.RefreshTokenDB.Create(rt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.GetJwtOutput{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// IssueMfaChallenge signs a short lived token proving the password of the user
+// was checked. It is only accepted in exchange for a second factor.
+func (t *TokenIssuer) IssueMfaChallenge(u *entity.User) (*dto.MfaChallengeOutput, error) {
+	now := time.Now()
+	_, challenge, err := t.Jwt.Encode(map[string]interface{}{
+		"sub":    u.ID,
+		"typ":    entity.TokenTypeMfaChallenge,
+		"jti":    utils.NewID().String(),
+		"iat":    now.Unix(),
+		"iat_ms": now.UnixMilli(),
+		"exp":    now.Add(time.Second * time.Duration(t.MfaChallengeExpiresIn)).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MfaChallengeOutput{
+		MfaRequired: true,
+		MfaToken:    challenge,
+		ExpiresIn:   t.MfaChallengeExpiresIn,
+	}, nil
+}
+
+// ParseMfaChallenge verifies a token from IssueMfaChallenge and returns it.
+// Its subject is the user it was issued to.
+func (t *TokenIssuer) ParseMfaChallenge(challenge string) (jwt.Token, error) {
+	token, err := t.Jwt.Verify(challenge)
+	if err != nil {
+		return nil, ErrInvalidMfaToken
+	}
+
+	typ, _ := token.Get("typ")
+	if typ != entity.TokenTypeMfaChallenge || token.Subject() == "" || token.JwtID() == "" {
+		return nil, ErrInvalidMfaToken
+	}
+
+	return token, nil
+}
+
+// IssueClientToken signs an access token for an OAuth client, which acts on
+// its own behalf within the granted scopes.
+func (t *TokenIssuer) IssueClientToken(client *entity.OAuthClient, scopes string) (*dto.OAuthTokenOutput, error) {
+	now := time.Now()
+	_, accessToken, err := t.Jwt.Encode(map[string]interface{}{
+		"sub":       client.ID,
+		"client_id": client.ID,
+		"typ":       entity.TokenTypeClient,
+		"scope":     scopes,
+		"jti":       utils.NewID().String(),
+		"iat":       now.Unix(),
+		"iat_ms":    now.UnixMilli(),
+		"exp":       now.Add(time.Second * time.Duration(t.ClientExpiresIn)).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.OAuthTokenOutput{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   t.ClientExpiresIn,
+		Scope:       scopes,
+	}, nil
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"app/internal/dto"
+	"app/internal/entity"
+	"app/internal/infra/database"
+	"app/pkg/totp"
+)
+
+type TwoFactorHandler struct {
+	UserDB            database.UserInterface
+	RecoveryCodeDB    database.RecoveryCodeInterface
+	TokenRevocationDB database.TokenRevocationInterface
+	Tokens            *TokenIssuer
+	LoginThrottler    *LoginThrottler
+	Audit             *Auditor
+	Issuer            string
+}
+
+func NewTwoFactorHandler(
+	UserDB database.UserInterface,
+	RecoveryCodeDB database.RecoveryCodeInterface,
+	TokenRevocationDB database.TokenRevocationInterface,
+	Tokens *TokenIssuer,
+	LoginThrottler *LoginThrottler,
+	Audit *Auditor,
+	Issuer string,
+) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		UserDB,
+		RecoveryCodeDB,
+		TokenRevocationDB,
+		Tokens,
+		LoginThrottler,
+		Audit,
+		Issuer,
+	}
+}
+
+// EnrollTotp godoc
+// @Summary      Enroll two-factor authentication
+// @Description  Generate a new TOTP secret for the authenticated user. It is only enabled once confirmed with a code.
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  dto.TotpEnrollOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/me/2fa/enroll [post]
+// @Security ApiKeyAuth
+func (h *TwoFactorHandler) EnrollTotp(w http.ResponseWriter, r *http.Request) {
+	userID, _ := currentUser(r)
+
+	u, err := h.UserDB.FindByID(userID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	secret, err := u.EnrollTotp()
+	if errors.Is(err, entity.ErrTotpAlreadyEnabled) {
+		writeProblem(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	if err == nil {
+		err = h.UserDB.Update(u)
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error enrolling two-factor authentication")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.TotpEnrollOutput{
+		Secret: secret,
+		URI:    totp.URI(h.Issuer, u.Email, secret),
+	})
+}
+
+// ConfirmTotp godoc
+// @Summary      Confirm two-factor authentication
+// @Description  Enable the enrolled TOTP secret with a code from the authenticator app. Returns one time recovery codes, shown only once.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.TotpConfirmInput  true  "TOTP code"
+// @Success      200  {object}  dto.RecoveryCodesOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/me/2fa/confirm [post]
+// @Security ApiKeyAuth
+func (h *TwoFactorHandler) ConfirmTotp(w http.ResponseWriter, r *http.Request) {
+	var input dto.TotpConfirmInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	userID, _ := currentUser(r)
+
+	u, err := h.UserDB.FindByID(userID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	err = u.ConfirmTotp(input.Code, time.Now())
+	if errors.Is(err, entity.ErrTotpAlreadyEnabled) {
+		writeProblem(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, plains, err := entity.NewRecoveryCodes(u.ID)
+	if err == nil {
+		err = h.RecoveryCodeDB.ReplaceForUser(u.ID, codes)
+	}
+	if err == nil {
+		err = h.UserDB.Update(u)
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error enabling two-factor authentication")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.RecoveryCodesOutput{RecoveryCodes: plains})
+}
+
+// DisableTotp godoc
+// @Summary      Disable two-factor authentication
+// @Description  Turn off two-factor authentication. Requires the password and a TOTP or recovery code.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body      dto.TotpDisableInput  true  "password and code"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/me/2fa/disable [post]
+// @Security ApiKeyAuth
+func (h *TwoFactorHandler) DisableTotp(w http.ResponseWriter, r *http.Request) {
+	var input dto.TotpDisableInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	userID, _ := currentUser(r)
+
+	u, err := h.UserDB.FindByID(userID)
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	if !u.TotpEnabled {
+		writeProblem(w, r, http.StatusBadRequest, entity.ErrTotpNotEnrolled.Error())
+		return
+	}
+
+	if !u.ValidatePassword(input.Password) || !h.verifySecondFactor(u, input.Code) {
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid password or code")
+		return
+	}
+
+	u.DisableTotp()
+	err = h.UserDB.Update(u)
+	if err == nil {
+		err = h.RecoveryCodeDB.DeleteForUser(u.ID)
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyMfa godoc
+// @Summary      Complete a two-factor login
+// @Description  Exchange the challenge token from generate-jwt and a TOTP or recovery code for a user JWT. A challenge is only accepted once, and not after the sessions of the user were ended.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.MfaVerifyInput  true  "challenge token and code"
+// @Success      200  {object}  dto.GetJwtOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/generate-jwt/mfa [post]
+func (h *TwoFactorHandler) VerifyMfa(w http.ResponseWriter, r *http.Request) {
+	wait, err := h.LoginThrottler.CheckIP(clientIP(r))
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if wait > 0 {
+		setRetryAfter(w, wait)
+		writeProblem(w, r, http.StatusTooManyRequests, "Too many login attempts")
+		return
+	}
+
+	var input dto.MfaVerifyInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	if input.MfaToken == "" {
+		writeRequiredField(w, r, "mfa_token")
+		return
+	}
+
+	challenge, err := h.Tokens.ParseMfaChallenge(input.MfaToken)
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid MFA token")
+		return
+	}
+
+	// A challenge is checked against revocations as access tokens are, so
+	// ending the sessions of the user, as a password reset does, ends it too.
+	issuedAt := entity.TokenIssuedAt(challenge.IssuedAt(), challenge.PrivateClaims())
+	revoked, err := h.TokenRevocationDB.IsRevoked(challenge.JwtID(), challenge.Subject(), issuedAt)
+	if err != nil {
+		writeRepositoryError(w, r, err, "Token")
+		return
+	}
+	if revoked {
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid MFA token")
+		return
+	}
+
+	u, err := h.UserDB.FindByID(challenge.Subject())
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+	if err != nil || !u.TotpEnabled || u.IsDisabled() {
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid MFA token")
+		return
+	}
+
+	// Codes are guessed against the same account lockout as passwords, as six
+	// digits alone would not survive a brute force for long.
+	wait, err = h.LoginThrottler.CheckAccount(u.Email)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if wait > 0 {
+		h.Audit.RecordLogin(r, entity.AuditLoginFailed, u.ID, u.Email, "account locked")
+		setRetryAfter(w, wait)
+		writeProblem(w, r, http.StatusTooManyRequests, "Too many failed login attempts")
+		return
+	}
+
+	if !h.verifySecondFactor(u, input.Code) {
+		h.Audit.RecordLogin(r, entity.AuditLoginFailed, u.ID, u.Email, "invalid two-factor code")
+		wait, _ := h.LoginThrottler.Failure(u.Email)
+		setRetryAfter(w, wait)
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	// The challenge is spent once it gets tokens.
+	err = h.TokenRevocationDB.Revoke(entity.NewRevokedToken(challenge.JwtID(), u.ID, challenge.Expiration()))
+	if err != nil {
+		writeRepositoryError(w, r, err, "Token")
+		return
+	}
+
+	if err := h.LoginThrottler.Success(u.Email); err != nil {
+		log.Printf("login throttle reset for user %s: %v", u.ID, err)
+	}
+
+	output, err := h.Tokens.Issue(u, "", nil)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error generating token")
+		return
+	}
+
+	h.Audit.RecordLogin(r, entity.AuditLoginSucceeded, u.ID, u.Email, "two-factor")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(output)
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code, consuming whichever matched.
+func (h *TwoFactorHandler) verifySecondFactor(u *entity.User, code string) bool {
+	if code == "" {
+		return false
+	}
+
+	if len(code) == totp.Digits {
+		if !u.VerifyTotp(code, time.Now()) {
+			return false
+		}
+		if err := h.UserDB.Update(u); err != nil {
+			log.Printf("two-factor step for user %s: %v", u.ID, err)
+			return false
+		}
+		return true
+	}
+
+	return h.RecoveryCodeDB.Use(u.ID, entity.HashRecoveryCode(code)) == nil
+}
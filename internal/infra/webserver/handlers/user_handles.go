@@ -2,8 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
-	"time"
 
 	"app/internal/dto"
 	"app/internal/entity"
@@ -12,74 +13,319 @@ import (
 	"github.com/go-chi/jwtauth"
 )
 
-type Error struct {
-	Message string `json:"message"`
-}
-
 type UserHandler struct {
-	UserDB       database.UserInterface
-	Jwt          *jwtauth.JWTAuth
-	JwtExpiresIn int
+	UserDB               database.UserInterface
+	RefreshTokenDB       database.RefreshTokenInterface
+	TokenRevocationDB    database.TokenRevocationInterface
+	Tokens               *TokenIssuer
+	EmailVerification    *EmailVerificationHandler
+	RequireVerifiedEmail bool
+	LoginThrottler       *LoginThrottler
+	Audit                *Auditor
 }
 
 func NewUserHandler(
 	UserDB database.UserInterface,
-	Jwt *jwtauth.JWTAuth,
-	JwtExpiresIn int,
+	RefreshTokenDB database.RefreshTokenInterface,
+	TokenRevocationDB database.TokenRevocationInterface,
+	Tokens *TokenIssuer,
+	EmailVerification *EmailVerificationHandler,
+	RequireVerifiedEmail bool,
+	LoginThrottler *LoginThrottler,
+	Audit *Auditor,
 ) *UserHandler {
 	return &UserHandler{
 		UserDB,
-		Jwt,
-		JwtExpiresIn,
+		RefreshTokenDB,
+		TokenRevocationDB,
+		Tokens,
+		EmailVerification,
+		RequireVerifiedEmail,
+		LoginThrottler,
+		Audit,
 	}
 }
 
 // GetJwt godoc
 // @Summary      Get a user JWT
-// @Description  Get a user JWT
+// @Description  Get a user JWT. Accounts with two-factor authentication get an MFA challenge instead.
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        request   body     dto.GetJwtInput  true  "user credentials"
 // @Success      200  {object}  dto.GetJwtOutput
-// @Failure      404  {object}  Error
-// @Failure      500  {object}  Error
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
 // @Router       /api/v1/users/generate-jwt [post]
 func (h *UserHandler) GetJwt(w http.ResponseWriter, r *http.Request) {
-	var jwtInput dto.GetJwtInput
-	err := json.NewDecoder(r.Body).Decode(&jwtInput)
+	wait, err := h.LoginThrottler.CheckIP(clientIP(r))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		err := Error{Message: "Invalid request body"}
+		writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if wait > 0 {
+		setRetryAfter(w, wait)
+		writeProblem(w, r, http.StatusTooManyRequests, "Too many login attempts")
+		return
+	}
 
-		json.NewEncoder(w).Encode(err)
+	var jwtInput dto.GetJwtInput
+	if !decodeJSON(w, r, &jwtInput) {
+		return
+	}
+
+	wait, err = h.LoginThrottler.CheckAccount(jwtInput.Email)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if wait > 0 {
+		h.Audit.RecordLogin(r, entity.AuditLoginFailed, "", jwtInput.Email, "account locked")
+		setRetryAfter(w, wait)
+		writeProblem(w, r, http.StatusTooManyRequests, "Too many failed login attempts")
 		return
 	}
 
 	u, err := h.UserDB.FindByEmail(jwtInput.Email)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+	if err != nil || !u.ValidatePassword(jwtInput.Password) {
+		userID := ""
+		if err == nil {
+			userID = u.ID
+		}
+		h.Audit.RecordLogin(r, entity.AuditLoginFailed, userID, jwtInput.Email, "invalid credentials")
+
+		wait, _ := h.LoginThrottler.Failure(jwtInput.Email)
+		setRetryAfter(w, wait)
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+
+	if err := h.LoginThrottler.Success(jwtInput.Email); err != nil {
+		log.Printf("login throttle reset for user %s: %v", u.ID, err)
+	}
+
+	h.upgradePasswordHash(u, jwtInput.Password)
+
+	if u.IsDisabled() {
+		h.Audit.RecordLogin(r, entity.AuditLoginFailed, u.ID, u.Email, "account disabled")
+		writeProblem(w, r, http.StatusForbidden, "Account disabled")
+		return
+	}
+
+	if u.PasswordResetRequired {
+		h.Audit.RecordLogin(r, entity.AuditLoginFailed, u.ID, u.Email, "password reset required")
+		writeProblem(w, r, http.StatusForbidden, "Password reset required")
+		return
+	}
+
+	if h.RequireVerifiedEmail && !u.IsEmailVerified() {
+		h.Audit.RecordLogin(r, entity.AuditLoginFailed, u.ID, u.Email, "email not verified")
+		writeProblem(w, r, http.StatusForbidden, "Email not verified")
+		return
+	}
+
+	// With two-factor authentication the password alone only buys a challenge
+	// token, exchanged for real tokens at /users/generate-jwt/mfa.
+	if u.TotpEnabled {
+		challenge, err := h.Tokens.IssueMfaChallenge(u)
+		if err != nil {
+			writeProblem(w, r, http.StatusInternalServerError, "Error generating token")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(challenge)
+		return
+	}
+
+	output, err := h.Tokens.Issue(u, "", nil)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		err := Error{Message: "Invalid email or password"}
-		json.NewEncoder(w).Encode(err)
+		writeProblem(w, r, http.StatusInternalServerError, "Error generating token")
 		return
 	}
 
-	if !u.ValidatePassword(jwtInput.Password) {
-		w.WriteHeader(http.StatusUnauthorized)
-		err := Error{Message: "Invalid email or password"}
-		json.NewEncoder(w).Encode(err)
+	h.Audit.RecordLogin(r, entity.AuditLoginSucceeded, u.ID, u.Email, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(output)
+}
+
+// upgradePasswordHash rehashes the password of a successful login when its
+// hash is weaker than the configured hasher makes. Failing only delays the
+// upgrade to the next login.
+func (h *UserHandler) upgradePasswordHash(u *entity.User, password string) {
+	if !u.PasswordNeedsRehash() {
 		return
 	}
 
-	_, tokenString, err := h.Jwt.Encode(map[string]interface{}{
-		"sub": u.ID,
-		"exp": time.Now().Add(time.Second * time.Duration(h.JwtExpiresIn)).Unix(),
-	})
+	err := u.RehashPassword(password)
+	if err == nil {
+		err = h.UserDB.Update(u)
+	}
+	if err != nil {
+		log.Printf("password rehash for user %s: %v", u.ID, err)
+	}
+}
 
-	accessToken := dto.GetJwtOutput{AccessToken: tokenString}
+// RefreshToken godoc
+// @Summary      Refresh a user JWT
+// @Description  Exchange a refresh token for a new access and refresh token pair
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.RefreshTokenInput  true  "refresh token"
+// @Success      200  {object}  dto.GetJwtOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/refresh-token [post]
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var input dto.RefreshTokenInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	if input.RefreshToken == "" {
+		writeRequiredField(w, r, "refresh_token")
+		return
+	}
+
+	current, err := h.RefreshTokenDB.FindByHash(entity.HashToken(input.RefreshToken))
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "Refresh token")
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	// A rotated token being presented again means it has leaked, so every
+	// token descending from the same login is revoked.
+	if current.IsRevoked() {
+		if err := h.RefreshTokenDB.RevokeFamily(current.FamilyID); err != nil {
+			writeRepositoryError(w, r, err, "Refresh token")
+			return
+		}
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	if current.IsExpired() {
+		writeProblem(w, r, http.StatusUnauthorized, "Refresh token expired")
+		return
+	}
+
+	u, err := h.UserDB.FindByID(current.UserID)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		writeRepositoryError(w, r, err, "User")
+		return
+	}
+	if err != nil || u.IsDisabled() {
+		if err := h.RefreshTokenDB.RevokeFamily(current.FamilyID); err != nil {
+			writeRepositoryError(w, r, err, "Refresh token")
+			return
+		}
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	// Rotate finds nothing to revoke only when a concurrent request rotated
+	// the same token first, which is a reuse like the one above.
+	output, err := h.Tokens.Issue(u, current.FamilyID, current)
+	if errors.Is(err, database.ErrNotFound) {
+		if err := h.RefreshTokenDB.RevokeFamily(current.FamilyID); err != nil {
+			writeRepositoryError(w, r, err, "Refresh token")
+			return
+		}
+		writeProblem(w, r, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		writeRepositoryError(w, r, err, "Refresh token")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(accessToken)
+	json.NewEncoder(w).Encode(output)
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke the current access token and, when given, the refresh token family
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.LogoutInput  false  "refresh token"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      413  {object}  problem.Problem
+// @Failure      415  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/logout [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	// The body is optional, only a refresh token to revoke along.
+	var input dto.LogoutInput
+	if r.ContentLength != 0 && !decodeJSON(w, r, &input) {
+		return
+	}
+
+	token, _, _ := jwtauth.FromContext(r.Context())
+
+	err := h.TokenRevocationDB.Revoke(entity.NewRevokedToken(token.JwtID(), token.Subject(), token.Expiration()))
+	if err != nil {
+		writeRepositoryError(w, r, err, "Token")
+		return
+	}
+
+	if input.RefreshToken != "" {
+		rt, err := h.RefreshTokenDB.FindByHash(entity.HashToken(input.RefreshToken))
+		if err == nil && rt.UserID == token.Subject() {
+			err = h.RefreshTokenDB.RevokeFamily(rt.FamilyID)
+		}
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			writeRepositoryError(w, r, err, "Refresh token")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary      Logout from all sessions
+// @Description  Revoke every access and refresh token issued to the current user
+// @Tags         users
+// @Produce      json
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/users/logout-all [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	token, _, _ := jwtauth.FromContext(r.Context())
+
+	err := revokeSessions(h.TokenRevocationDB, h.RefreshTokenDB, token.Subject())
+	if err != nil {
+		writeRepositoryError(w, r, err, "Token")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // Create user godoc
@@ -90,31 +336,39 @@ func (h *UserHandler) GetJwt(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        request     body      dto.CreateUserInput  true  "user request"
 // @Success      201
-// @Failure      500         {object}  Error
+// @Failure      400         {object}  problem.Problem
+// @Failure      409         {object}  problem.Problem
+// @Failure      413         {object}  problem.Problem
+// @Failure      415         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
 // @Router       /api/v1/users [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var user dto.CreateUserInput
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodeJSON(w, r, &user) {
 		return
 	}
 
 	u, err := entity.NewUser(user.Name, user.Email, user.Password)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		err := Error{Message: "Invalid user data"}
-		json.NewEncoder(w).Encode(err)
+		writeValidationProblem(w, r, err, nil)
 		return
 	}
 
 	err = h.UserDB.Create(u)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		err := Error{Message: "Error creating user"}
-		json.NewEncoder(w).Encode(err)
+		writeRepositoryError(w, r, err, "User")
 		return
 	}
 
+	event := entity.NewAuditEvent(entity.AuditUserCreated, "user", u.ID)
+	if err := event.SetSnapshots(nil, newUserOutput(u)); err != nil {
+		log.Printf("audit snapshot of user %s: %v", u.ID, err)
+	}
+	h.Audit.Record(r, event)
+
+	if err := h.EmailVerification.SendVerificationEmail(u); err != nil {
+		log.Printf("email verification for user %s: %v", u.ID, err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 }
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"app/internal/entity"
+	"app/pkg/problem"
+
+	"github.com/go-chi/jwtauth"
+)
+
+// RequireAccessToken must be placed after Authenticator. It answers
+// 401 for tokens signed by us for another purpose, such as an MFA challenge.
+func RequireAccessToken(next http.Handler) http.Handler {
+	return RequireTokenType(entity.TokenTypeAccess)(next)
+}
+
+// RequireTokenType must be placed after Authenticator. It answers 401
+// unless the typ claim is one of the given types.
+func RequireTokenType(types ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, claims, _ := jwtauth.FromContext(r.Context())
+
+			typ, _ := claims["typ"].(string)
+			for _, t := range types {
+				if typ == t {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			problem.Write(w, r, http.StatusUnauthorized, "")
+		})
+	}
+}
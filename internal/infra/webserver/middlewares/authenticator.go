@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+	"app/pkg/jwtkeys"
+	"app/pkg/problem"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// APIKeyHeader carries the API keys of machine clients.
+const APIKeyHeader = "X-API-Key"
+
+// lastUsedResolution bounds how often a busy key writes its last use.
+const lastUsedResolution = time.Minute
+
+// Authenticate accepts either an API key in the X-API-Key header or a JWT
+// access token of a user or an OAuth client, checked as Verifier,
+// Authenticator, RequireTokenType and RejectRevokedTokens would. API
+// keys act as their owner, with the role the owner has now, and put a token
+// with their scopes in the context, so RequirePermission and
+// jwtauth.FromContext work for all of them.
+func Authenticate(
+	keys *jwtkeys.KeyRing,
+	revocations database.TokenRevocationInterface,
+	apiKeys database.APIKeyInterface,
+	users database.UserInterface,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withJwt := Verifier(keys)(Authenticator(
+			RequireTokenType(entity.TokenTypeAccess, entity.TokenTypeClient)(
+				RejectRevokedTokens(revocations)(next),
+			),
+		))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plain := r.Header.Get(APIKeyHeader)
+			if plain == "" {
+				withJwt.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := apiKeys.FindByHash(entity.HashToken(plain))
+			if err != nil || key.IsRevoked() || key.IsExpired() {
+				problem.Write(w, r, http.StatusUnauthorized, "Invalid API key")
+				return
+			}
+
+			u, err := users.FindByID(key.UserID)
+			if err != nil || u.IsDisabled() {
+				problem.Write(w, r, http.StatusUnauthorized, "Invalid API key")
+				return
+			}
+
+			now := time.Now()
+			if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+				if err := apiKeys.TouchLastUsed(key.ID, now); err != nil {
+					log.Printf("api key %s last use: %v", key.ID, err)
+				}
+			}
+
+			token := jwt.New()
+			token.Set(jwt.SubjectKey, u.ID)
+			token.Set(jwt.JwtIDKey, key.ID)
+			token.Set("typ", entity.TokenTypeAPIKey)
+			token.Set("role", string(u.Role))
+			token.Set("scope", key.Scopes)
+
+			ctx := jwtauth.NewContext(r.Context(), token, nil)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
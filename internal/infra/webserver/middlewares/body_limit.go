@@ -0,0 +1,24 @@
+package middlewares
+
+import "net/http"
+
+// DefaultMaxBodySize is the body limit used when none is configured, 1 MiB.
+const DefaultMaxBodySize int64 = 1 << 20
+
+// LimitBodySize caps every request body at limit bytes, or at
+// DefaultMaxBodySize when limit is not positive. Reading past it fails with an
+// *http.MaxBytesError, which handlers answer with 413.
+func LimitBodySize(limit int64) func(http.Handler) http.Handler {
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"app/internal/entity"
+	"app/pkg/problem"
+
+	"github.com/go-chi/jwtauth"
+)
+
+// RequirePermission must be placed after Authenticate. Users need the role,
+// API keys need both the role of their owner and the scope, and OAuth clients,
+// which have no role, need the scope. It answers 403 otherwise.
+func RequirePermission(role entity.Role, scope entity.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, claims, _ := jwtauth.FromContext(r.Context())
+
+			typ, _ := claims["typ"].(string)
+			claimedRole, _ := claims["role"].(string)
+			granted, hasScopes := claims["scope"].(string)
+
+			if typ != entity.TokenTypeClient && !entity.Role(claimedRole).Includes(role) {
+				problem.Write(w, r, http.StatusForbidden, "")
+				return
+			}
+
+			if (hasScopes || typ == entity.TokenTypeClient) && !entity.HasScope(granted, scope) {
+				problem.Write(w, r, http.StatusForbidden, "Insufficient scope")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
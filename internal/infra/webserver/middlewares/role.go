@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"app/internal/entity"
+	"app/pkg/problem"
+
+	"github.com/go-chi/jwtauth"
+)
+
+// RequireRole must be placed after Authenticator. It answers 403 when
+// the role claim of the token does not include the given role.
+func RequireRole(role entity.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, claims, _ := jwtauth.FromContext(r.Context())
+
+			claim, _ := claims["role"].(string)
+			if !entity.Role(claim).Includes(role) {
+				problem.Write(w, r, http.StatusForbidden, "")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
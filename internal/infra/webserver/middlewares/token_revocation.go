@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"app/internal/entity"
+	"app/internal/infra/database"
+	"app/pkg/problem"
+
+	"github.com/go-chi/jwtauth"
+)
+
+// RejectRevokedTokens must be placed after Authenticator. It answers
+// 401 for tokens without a jti claim or that were revoked through logout.
+func RejectRevokedTokens(store database.TokenRevocationInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil || token == nil || token.JwtID() == "" {
+				problem.Write(w, r, http.StatusUnauthorized, "")
+				return
+			}
+
+			issuedAt := entity.TokenIssuedAt(token.IssuedAt(), claims)
+			revoked, err := store.IsRevoked(token.JwtID(), token.Subject(), issuedAt)
+			if err != nil {
+				problem.Write(w, r, http.StatusInternalServerError, "")
+				return
+			}
+
+			if revoked {
+				problem.Write(w, r, http.StatusUnauthorized, "Token is revoked")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"app/pkg/jwtkeys"
+	"app/pkg/problem"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// Verifier works like jwtauth.Verifier but checks tokens against every key of
+// the ring, so tokens signed before a key rotation stay valid. The result is
+// stored in the context for Authenticator and jwtauth.FromContext.
+func Verifier(keys *jwtkeys.KeyRing) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := jwtauth.TokenFromHeader(r)
+			if tokenString == "" {
+				tokenString = jwtauth.TokenFromCookie(r)
+			}
+
+			if tokenString == "" {
+				ctx := jwtauth.NewContext(r.Context(), nil, jwtauth.ErrNoTokenFound)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			token, err := keys.Verify(tokenString)
+			if err != nil {
+				err = jwtauth.ErrorReason(err)
+			}
+
+			ctx := jwtauth.NewContext(r.Context(), token, err)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Authenticator works like jwtauth.Authenticator, answering 401 when Verifier
+// found no valid token, but with a problem+json body.
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _, err := jwtauth.FromContext(r.Context())
+		if err != nil {
+			problem.Write(w, r, http.StatusUnauthorized, capitalize(err.Error()))
+			return
+		}
+
+		if token == nil || jwt.Validate(token) != nil {
+			problem.Write(w, r, http.StatusUnauthorized, "")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// capitalize starts the messages of library errors, such as "token is
+// expired", like the other problem details.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
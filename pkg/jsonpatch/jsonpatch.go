@@ -0,0 +1,338 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchType is the media type of RFC 7396 merge patches.
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType is the media type of RFC 6902 patches.
+	JSONPatchType = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidOperation = errors.New("invalid operation")
+	ErrInvalidPointer   = errors.New("invalid JSON pointer")
+	ErrPathNotFound     = errors.New("path not found")
+	ErrTestFailed       = errors.New("test failed")
+)
+
+// Operation is one step of an RFC 6902 patch. Value is kept raw so a missing
+// value can be told apart from null.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// MergePatch applies an RFC 7396 merge patch to doc: members of the patch
+// replace those of doc, null members remove them and objects merge
+// recursively.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(target, changes))
+}
+
+func merge(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	result := map[string]interface{}{}
+	if members, ok := target.(map[string]interface{}); ok {
+		for k, v := range members {
+			result[k] = v
+		}
+	}
+
+	for k, v := range changes {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = merge(result[k], v)
+	}
+
+	return result
+}
+
+// Apply runs the operations of an RFC 6902 patch on doc, in order. It fails
+// with the first operation that cannot be applied, leaving doc untouched.
+func Apply(doc []byte, ops []Operation) ([]byte, error) {
+	var root interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		root, err = apply(root, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func apply(root interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+
+	case "remove":
+		if len(path) == 0 {
+			return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidOperation)
+		}
+		root, _, err := remove(root, path)
+		return root, err
+
+	case "replace":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		root, _, err = remove(root, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+
+	case "move":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidOperation)
+		}
+		if len(from) == 0 {
+			return nil, fmt.Errorf("%w: cannot move the whole document", ErrInvalidOperation)
+		}
+		root, value, err := remove(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+
+	case "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(root, from)
+		if err != nil {
+			return nil, err
+		}
+		value, err = deepCopy(value)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+
+	case "test":
+		expected, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			return nil, ErrTestFailed
+		}
+		return root, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidOperation, op.Op)
+}
+
+func (op Operation) value() (interface{}, error) {
+	if len(op.Value) == 0 {
+		return nil, fmt.Errorf("%w: %s needs a value", ErrInvalidOperation, op.Op)
+	}
+
+	var value interface{}
+	err := json.Unmarshal(op.Value, &value)
+	return value, err
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped reference
+// tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPointer, pointer)
+	}
+
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = unescape.Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// index parses an array index token. With appending, "-" is the position
+// after the last element.
+func index(token string, length int, appending bool) (int, error) {
+	if appending && token == "-" {
+		return length, nil
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || strconv.Itoa(i) != token {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalidPointer, token)
+	}
+
+	limit := length - 1
+	if appending {
+		limit = length
+	}
+	if i < 0 || i > limit {
+		return 0, fmt.Errorf("%w: index %d out of range", ErrPathNotFound, i)
+	}
+
+	return i, nil
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q", ErrPathNotFound, token)
+			}
+			node = child
+		case []interface{}:
+			i, err := index(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("%w: %q is not in a container", ErrPathNotFound, token)
+		}
+	}
+
+	return node, nil
+}
+
+// add returns node with value added at path. The parent of path must exist.
+func add(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, last := path[0], len(path) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if last {
+			n[token] = value
+			return n, nil
+		}
+		child, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q", ErrPathNotFound, token)
+		}
+		child, err := add(child, path[1:], value)
+		n[token] = child
+		return n, err
+
+	case []interface{}:
+		i, err := index(token, len(n), last)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+		child, err := add(n[i], path[1:], value)
+		n[i] = child
+		return n, err
+	}
+
+	return nil, fmt.Errorf("%w: %q is not in a container", ErrPathNotFound, token)
+}
+
+// remove returns node without the value at path, and that value.
+func remove(node interface{}, path []string) (interface{}, interface{}, error) {
+	token, last := path[0], len(path) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: member %q", ErrPathNotFound, token)
+		}
+		if last {
+			delete(n, token)
+			return n, child, nil
+		}
+		child, removed, err := remove(child, path[1:])
+		n[token] = child
+		return n, removed, err
+
+	case []interface{}:
+		i, err := index(token, len(n), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if last {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		child, removed, err := remove(n[i], path[1:])
+		n[i] = child
+		return n, removed, err
+	}
+
+	return nil, nil, fmt.Errorf("%w: %q is not in a container", ErrPathNotFound, token)
+}
+
+func deepCopy(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied interface{}
+	err = json.Unmarshal(raw, &copied)
+	return copied, err
+}
@@ -0,0 +1,96 @@
+package jsonpatch_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"app/pkg/jsonpatch"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Test cases from RFC 7396, appendix A.
+	for _, c := range []struct{ doc, patch, expected string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		result, err := jsonpatch.MergePatch([]byte(c.doc), []byte(c.patch))
+		assert.Nil(t, err)
+		assert.JSONEq(t, c.expected, string(result), c.patch)
+	}
+}
+
+func TestApply(t *testing.T) {
+	// Test cases from RFC 6902, appendix A.
+	for _, c := range []struct{ doc, patch, expected string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, `{"foo":{"bar":1},"baz":{"bar":2}}`},
+	} {
+		var ops []jsonpatch.Operation
+		assert.Nil(t, json.Unmarshal([]byte(c.patch), &ops))
+
+		result, err := jsonpatch.Apply([]byte(c.doc), ops)
+		assert.Nil(t, err, c.patch)
+		assert.JSONEq(t, c.expected, string(result), c.patch)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	for _, c := range []struct {
+		doc, patch string
+		expected   error
+	}{
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, jsonpatch.ErrTestFailed},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, jsonpatch.ErrPathNotFound},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, jsonpatch.ErrPathNotFound},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`, jsonpatch.ErrPathNotFound},
+		{`{"foo":[1]}`, `[{"op":"add","path":"/foo/2","value":1}]`, jsonpatch.ErrPathNotFound},
+		{`{"foo":[1]}`, `[{"op":"add","path":"/foo/01","value":1}]`, jsonpatch.ErrInvalidPointer},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"foo","value":1}]`, jsonpatch.ErrInvalidPointer},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, jsonpatch.ErrInvalidOperation},
+		{`{"foo":"bar"}`, `[{"op":"merge","path":"/foo"}]`, jsonpatch.ErrInvalidOperation},
+		{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, jsonpatch.ErrInvalidOperation},
+	} {
+		var ops []jsonpatch.Operation
+		assert.Nil(t, json.Unmarshal([]byte(c.patch), &ops))
+
+		_, err := jsonpatch.Apply([]byte(c.doc), ops)
+		assert.ErrorIs(t, err, c.expected, c.patch)
+	}
+}
+
+func TestApplyLeavesDocumentOnFailure(t *testing.T) {
+	doc := []byte(`{"foo":"bar"}`)
+	ops := []jsonpatch.Operation{
+		{Op: "replace", Path: "/foo", Value: json.RawMessage(`"baz"`)},
+		{Op: "test", Path: "/foo", Value: json.RawMessage(`"bar"`)},
+	}
+
+	result, err := jsonpatch.Apply(doc, ops)
+	assert.ErrorIs(t, err, jsonpatch.ErrTestFailed)
+	assert.Nil(t, result)
+	assert.JSONEq(t, `{"foo":"bar"}`, string(doc))
+}
@@ -0,0 +1,212 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrKeyMismatch          = errors.New("key does not match the signing algorithm")
+	ErrNoPEMBlock           = errors.New("no PEM block found")
+	ErrNotSigningKey        = errors.New("key cannot sign tokens")
+)
+
+// Key is a signing or verification key identified by its RFC 7638 thumbprint.
+type Key struct {
+	ID        string
+	Algorithm jwa.SignatureAlgorithm
+	// NotAfter is set once the key is retired. Tokens signed with it are
+	// rejected afterwards, when all of them have expired anyway.
+	NotAfter time.Time
+
+	private jwk.Key
+	public  jwk.Key
+}
+
+// NewSecretKey returns a HMAC key for HS256, HS384 or HS512.
+func NewSecretKey(alg string, secret []byte) (*Key, error) {
+	switch jwa.SignatureAlgorithm(alg) {
+	case jwa.HS256, jwa.HS384, jwa.HS512:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%w: empty secret", ErrKeyMismatch)
+	}
+
+	return newKey(jwa.SignatureAlgorithm(alg), secret)
+}
+
+// LoadPrivateKey reads a PKCS #8, PKCS #1 or SEC 1 private key and checks it
+// fits the algorithm, one of RS256, ES256 or EdDSA.
+func LoadPrivateKey(alg string, path string) (*Key, error) {
+	raw, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	private, err := parsePrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := checkAlgorithm(jwa.SignatureAlgorithm(alg), private); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return newKey(jwa.SignatureAlgorithm(alg), private)
+}
+
+// LoadVerificationKey reads a public or private key only kept to verify tokens
+// signed before a rotation. The algorithm is derived from the key type.
+func LoadVerificationKey(path string) (*Key, error) {
+	raw, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parsePrivateKey(raw)
+	if err != nil {
+		key, err = x509.ParsePKIXPublicKey(raw.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	alg, err := algorithmOf(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	k, err := newKey(alg, key)
+	if err != nil {
+		return nil, err
+	}
+
+	k.private = nil
+	return k, nil
+}
+
+// IsSymmetric reports whether the key is a shared secret, never published.
+func (k *Key) IsSymmetric() bool {
+	switch k.Algorithm {
+	case jwa.HS256, jwa.HS384, jwa.HS512:
+		return true
+	}
+	return false
+}
+
+func (k *Key) isRetiredAt(now time.Time) bool {
+	return !k.NotAfter.IsZero() && !now.Before(k.NotAfter)
+}
+
+func newKey(alg jwa.SignatureAlgorithm, raw interface{}) (*Key, error) {
+	key, err := jwk.New(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	public := key
+	if _, ok := raw.([]byte); !ok {
+		public, err = jwk.PublicKeyOf(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	thumbprint, err := public.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	kid := base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	for _, k := range []jwk.Key{key, public} {
+		k.Set(jwk.KeyIDKey, kid)
+		k.Set(jwk.AlgorithmKey, alg)
+		k.Set(jwk.KeyUsageKey, jwk.ForSignature)
+	}
+
+	return &Key{ID: kid, Algorithm: alg, private: key, public: public}, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoPEMBlock)
+	}
+
+	return block, nil
+}
+
+func parsePrivateKey(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+}
+
+func checkAlgorithm(alg jwa.SignatureAlgorithm, key interface{}) error {
+	switch alg {
+	case jwa.RS256, jwa.ES256, jwa.EdDSA:
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+
+	expected, err := algorithmOf(key)
+	if err != nil {
+		return err
+	}
+
+	if expected != alg {
+		return fmt.Errorf("%w: %s key for %s", ErrKeyMismatch, expected, alg)
+	}
+
+	return nil
+}
+
+// algorithmOf maps a key to the algorithm we sign with for its type.
+func algorithmOf(key interface{}) (jwa.SignatureAlgorithm, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return jwa.RS256, nil
+	case *ecdsa.PrivateKey:
+		return ecdsaAlgorithm(k.Curve)
+	case *ecdsa.PublicKey:
+		return ecdsaAlgorithm(k.Curve)
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwa.EdDSA, nil
+	default:
+		return "", fmt.Errorf("%w: %T", ErrUnsupportedAlgorithm, key)
+	}
+}
+
+func ecdsaAlgorithm(curve elliptic.Curve) (jwa.SignatureAlgorithm, error) {
+	if curve != elliptic.P256() {
+		return "", fmt.Errorf("%w: curve %s", ErrUnsupportedAlgorithm, curve.Params().Name)
+	}
+	return jwa.ES256, nil
+}
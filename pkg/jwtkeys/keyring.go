@@ -0,0 +1,177 @@
+package jwtkeys
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+var (
+	ErrMissingKeyID = errors.New("token has no key ID")
+	ErrUnknownKey   = errors.New("token is signed with an unknown key")
+)
+
+// KeyRing signs tokens with its current key and verifies them with any key
+// it still knows, picked by the kid header. Retired keys are kept for the
+// retention, the longest lifetime of a token, then forgotten.
+type KeyRing struct {
+	mu        sync.RWMutex
+	signing   *Key
+	retired   []*Key
+	retention time.Duration
+}
+
+func NewKeyRing(signing *Key, retention time.Duration) (*KeyRing, error) {
+	if signing.private == nil {
+		return nil, ErrNotSigningKey
+	}
+
+	return &KeyRing{signing: signing, retention: retention}, nil
+}
+
+// Retire adds a key of a previous rotation. It is accepted for verification
+// for the retention from now, as every token it signed was issued earlier.
+func (r *KeyRing) Retire(key *Key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.retire(key, time.Now())
+}
+
+// Rotate makes next the signing key and retires the current one.
+func (r *KeyRing) Rotate(next *Key) error {
+	if next.private == nil {
+		return ErrNotSigningKey
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if next.ID == r.signing.ID {
+		return nil
+	}
+
+	r.retire(r.signing, time.Now())
+	r.signing = next
+	r.retired = removeKey(r.retired, next.ID)
+	return nil
+}
+
+// Keys returns the signing key first, then every retired key still valid.
+func (r *KeyRing) Keys() []*Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	keys := []*Key{r.signing}
+	for _, k := range r.retired {
+		if !k.isRetiredAt(now) {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+// PublicSet returns the JWKS of the asymmetric keys of the ring.
+func (r *KeyRing) PublicSet() jwk.Set {
+	set := jwk.NewSet()
+	for _, k := range r.Keys() {
+		if !k.IsSymmetric() {
+			set.Add(k.public)
+		}
+	}
+
+	return set
+}
+
+// Encode signs the claims with the current key, naming it in the kid header.
+func (r *KeyRing) Encode(claims map[string]interface{}) (jwt.Token, string, error) {
+	r.mu.RLock()
+	signing := r.signing
+	r.mu.RUnlock()
+
+	t := jwt.New()
+	for k, v := range claims {
+		if err := t.Set(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+
+	payload, err := jwt.Sign(t, signing.Algorithm, signing.private)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return t, string(payload), nil
+}
+
+// Decode verifies the signature of the token with the key named by its kid
+// header. Claims such as exp are checked by Verify.
+func (r *KeyRing) Decode(tokenString string) (jwt.Token, error) {
+	msg, err := jws.ParseString(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, ErrUnknownKey
+	}
+
+	headers := msg.Signatures()[0].ProtectedHeaders()
+	if headers.KeyID() == "" {
+		return nil, ErrMissingKeyID
+	}
+
+	key := r.lookup(headers.KeyID())
+	if key == nil || headers.Algorithm() != key.Algorithm {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, headers.KeyID())
+	}
+
+	return jwt.ParseString(tokenString, jwt.WithVerify(key.Algorithm, key.public))
+}
+
+// Verify decodes the token and validates its time based claims.
+func (r *KeyRing) Verify(tokenString string) (jwt.Token, error) {
+	token, err := r.Decode(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := jwt.Validate(token); err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+func (r *KeyRing) lookup(kid string) *Key {
+	for _, k := range r.Keys() {
+		if k.ID == kid {
+			return k
+		}
+	}
+
+	return nil
+}
+
+func (r *KeyRing) retire(key *Key, now time.Time) {
+	retired := *key
+	retired.NotAfter = now.Add(r.retention)
+	r.retired = append(removeKey(r.retired, key.ID), &retired)
+}
+
+func removeKey(keys []*Key, kid string) []*Key {
+	kept := keys[:0]
+	for _, k := range keys {
+		if k.ID != kid {
+			kept = append(kept, k)
+		}
+	}
+
+	return kept
+}
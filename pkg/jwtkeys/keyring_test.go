@@ -0,0 +1,165 @@
+package jwtkeys_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"app/pkg/jwtkeys"
+
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, key interface{}, public bool) string {
+	var (
+		der       []byte
+		blockType string
+		err       error
+	)
+
+	if public {
+		blockType = "PUBLIC KEY"
+		der, err = x509.MarshalPKIXPublicKey(key)
+	} else {
+		blockType = "PRIVATE KEY"
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	return path
+}
+
+func newPrivateKeys(t *testing.T) map[string]interface{} {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return map[string]interface{}{
+		"RS256": rsaKey,
+		"ES256": ecKey,
+		"EdDSA": edKey,
+	}
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	for alg, private := range newPrivateKeys(t) {
+		t.Run(alg, func(t *testing.T) {
+			key, err := jwtkeys.LoadPrivateKey(alg, writeKey(t, private, false))
+			require.NoError(t, err)
+			assert.Equal(t, alg, key.Algorithm.String())
+			assert.NotEmpty(t, key.ID)
+
+			ring, err := jwtkeys.NewKeyRing(key, time.Hour)
+			require.NoError(t, err)
+
+			_, signed, err := ring.Encode(map[string]interface{}{"sub": "user", "exp": time.Now().Add(time.Minute).Unix()})
+			require.NoError(t, err)
+
+			msg, err := jws.ParseString(signed)
+			require.NoError(t, err)
+			assert.Equal(t, key.ID, msg.Signatures()[0].ProtectedHeaders().KeyID())
+
+			token, err := ring.Verify(signed)
+			require.NoError(t, err)
+			assert.Equal(t, "user", token.Subject())
+		})
+	}
+}
+
+func TestLoadPrivateKey_AlgorithmMismatch(t *testing.T) {
+	keys := newPrivateKeys(t)
+
+	_, err := jwtkeys.LoadPrivateKey("ES256", writeKey(t, keys["RS256"], false))
+	assert.ErrorIs(t, err, jwtkeys.ErrKeyMismatch)
+
+	_, err = jwtkeys.LoadPrivateKey("PS512", writeKey(t, keys["RS256"], false))
+	assert.ErrorIs(t, err, jwtkeys.ErrUnsupportedAlgorithm)
+}
+
+func TestLoadVerificationKey(t *testing.T) {
+	keys := newPrivateKeys(t)
+	private := keys["EdDSA"].(ed25519.PrivateKey)
+
+	signing, err := jwtkeys.LoadPrivateKey("EdDSA", writeKey(t, private, false))
+	require.NoError(t, err)
+	public, err := jwtkeys.LoadVerificationKey(writeKey(t, private.Public(), true))
+	require.NoError(t, err)
+	assert.Equal(t, signing.ID, public.ID)
+
+	_, err = jwtkeys.NewKeyRing(public, time.Hour)
+	assert.ErrorIs(t, err, jwtkeys.ErrNotSigningKey)
+}
+
+func TestKeyRing_Rotate(t *testing.T) {
+	keys := newPrivateKeys(t)
+	first, err := jwtkeys.LoadPrivateKey("RS256", writeKey(t, keys["RS256"], false))
+	require.NoError(t, err)
+	second, err := jwtkeys.LoadPrivateKey("EdDSA", writeKey(t, keys["EdDSA"], false))
+	require.NoError(t, err)
+
+	ring, err := jwtkeys.NewKeyRing(first, time.Hour)
+	require.NoError(t, err)
+
+	_, before, err := ring.Encode(map[string]interface{}{"sub": "user"})
+	require.NoError(t, err)
+
+	require.NoError(t, ring.Rotate(second))
+	_, after, err := ring.Encode(map[string]interface{}{"sub": "user"})
+	require.NoError(t, err)
+
+	_, err = ring.Verify(before)
+	assert.NoError(t, err)
+	_, err = ring.Verify(after)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ring.PublicSet().Len())
+
+	expiring, err := jwtkeys.NewKeyRing(first, -time.Second)
+	require.NoError(t, err)
+	_, old, err := expiring.Encode(map[string]interface{}{"sub": "user"})
+	require.NoError(t, err)
+	require.NoError(t, expiring.Rotate(second))
+
+	_, err = expiring.Verify(old)
+	assert.ErrorIs(t, err, jwtkeys.ErrUnknownKey)
+	assert.Equal(t, 1, expiring.PublicSet().Len())
+}
+
+func TestKeyRing_SecretKey(t *testing.T) {
+	key, err := jwtkeys.NewSecretKey("HS256", []byte("secret"))
+	require.NoError(t, err)
+
+	ring, err := jwtkeys.NewKeyRing(key, time.Hour)
+	require.NoError(t, err)
+
+	_, signed, err := ring.Encode(map[string]interface{}{"sub": "user"})
+	require.NoError(t, err)
+
+	_, err = ring.Verify(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ring.PublicSet().Len())
+
+	other, err := jwtkeys.NewSecretKey("HS256", []byte("other"))
+	require.NoError(t, err)
+	otherRing, err := jwtkeys.NewKeyRing(other, time.Hour)
+	require.NoError(t, err)
+
+	_, err = otherRing.Verify(signed)
+	assert.ErrorIs(t, err, jwtkeys.ErrUnknownKey)
+}
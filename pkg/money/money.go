@@ -0,0 +1,193 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrUnknownCurrency = errors.New("unknown currency")
+)
+
+// minorUnits is the number of decimals of the ISO 4217 currencies accepted,
+// a subset of the standard covering the currencies in common use.
+var minorUnits = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2,
+	"CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2,
+	"GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "IQD": 3,
+	"ISK": 0, "JOD": 3, "JPY": 0, "KES": 2, "KRW": 0, "KWD": 3, "LYD": 3,
+	"MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PEN": 2,
+	"PHP": 2, "PLN": 2, "RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2,
+	"TND": 3, "TRY": 2, "UAH": 2, "UGX": 0, "USD": 2, "UYU": 2, "VND": 0,
+	"XAF": 0, "XOF": 0, "ZAR": 2,
+}
+
+// Money is an exact amount of a currency, counted in its minor unit, such as
+// cents for USD. The zero value has no currency and stands for no amount.
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) (Money, error) {
+	if _, err := MinorUnits(currency); err != nil {
+		return Money{}, err
+	}
+
+	return Money{amount, currency}, nil
+}
+
+// Parse reads a decimal amount of currency, such as "197.98" USD. It refuses
+// more decimals than the currency has, so amounts are never rounded.
+func Parse(amount, currency string) (Money, error) {
+	units, err := MinorUnits(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	digits, negative := strings.CutPrefix(amount, "-")
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidAmount, amount)
+	}
+	if len(fraction) > units {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, amount, units, currency)
+	}
+
+	var minor int64
+	for _, d := range whole + fraction + strings.Repeat("0", units-len(fraction)) {
+		if minor > (math.MaxInt64-int64(d-'0'))/10 {
+			return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, amount)
+		}
+		minor = minor*10 + int64(d-'0')
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Money{minor, currency}, nil
+}
+
+// MustParse is like Parse but panics when the amount is invalid, for amounts
+// known to be valid such as constants.
+func MustParse(amount, currency string) Money {
+	m, err := Parse(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// MinorUnits returns the number of decimals of an ISO 4217 currency code.
+func MinorUnits(currency string) (int, error) {
+	units, ok := minorUnits[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return units, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Amount is the amount in minor units of the currency.
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+func (m Money) Currency() string {
+	return m.currency
+}
+
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+// Decimal formats the amount with all the decimals of its currency, such as
+// "197.90".
+func (m Money) Decimal() string {
+	units := minorUnits[m.currency]
+
+	sign, abs := "", uint64(m.amount)
+	if m.amount < 0 {
+		sign, abs = "-", uint64(-m.amount)
+	}
+
+	digits := fmt.Sprintf("%0*d", units+1, abs)
+	if units == 0 {
+		return sign + digits
+	}
+	return sign + digits[:len(digits)-units] + "." + digits[len(digits)-units:]
+}
+
+// String formats the money as its currency code and decimal amount, such as
+// "USD 197.90".
+func (m Money) String() string {
+	return m.currency + " " + m.Decimal()
+}
+
+type jsonMoney struct {
+	Amount   *string `json:"amount"`
+	Currency *string `json:"currency"`
+}
+
+// MarshalJSON encodes the money as an object holding its decimal amount as a
+// string, so it never goes through a float, and its currency code. The zero
+// value is null.
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m.currency == "" {
+		return []byte("null"), nil
+	}
+
+	amount := m.Decimal()
+	return json.Marshal(jsonMoney{&amount, &m.currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Money{}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var v jsonMoney
+	var typeErr *json.UnmarshalTypeError
+	if err := dec.Decode(&v); errors.As(err, &typeErr) {
+		return fmt.Errorf("%w: expected an object with a decimal string amount and a currency code", ErrInvalidAmount)
+	} else if err != nil {
+		return err
+	}
+	if v.Amount == nil {
+		return fmt.Errorf("%w: amount is required", ErrInvalidAmount)
+	}
+	if v.Currency == nil {
+		return fmt.Errorf("%w: currency is required", ErrUnknownCurrency)
+	}
+
+	parsed, err := Parse(*v.Amount, *v.Currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,91 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"app/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		amount, currency string
+		minor            int64
+		decimal          string
+	}{
+		{"197.98", "USD", 19798, "197.98"},
+		{"197.9", "USD", 19790, "197.90"},
+		{"10", "EUR", 1000, "10.00"},
+		{"0.05", "BRL", 5, "0.05"},
+		{"-1.5", "USD", -150, "-1.50"},
+		{"-0.01", "USD", -1, "-0.01"},
+		{"1500", "JPY", 1500, "1500"},
+		{"1.234", "KWD", 1234, "1.234"},
+	} {
+		m, err := money.Parse(c.amount, c.currency)
+		assert.NoError(t, err, c.amount)
+		assert.Equal(t, c.minor, m.Amount(), c.amount)
+		assert.Equal(t, c.currency, m.Currency())
+		assert.Equal(t, c.decimal, m.Decimal(), c.amount)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		amount, currency string
+		expected         error
+	}{
+		{"1.999", "USD", money.ErrInvalidAmount},
+		{"1.5", "JPY", money.ErrInvalidAmount},
+		{"", "USD", money.ErrInvalidAmount},
+		{"1.", "USD", money.ErrInvalidAmount},
+		{".5", "USD", money.ErrInvalidAmount},
+		{"+1", "USD", money.ErrInvalidAmount},
+		{"1e3", "USD", money.ErrInvalidAmount},
+		{" 1", "USD", money.ErrInvalidAmount},
+		{"92233720368547758.08", "USD", money.ErrInvalidAmount},
+		{"1", "usd", money.ErrUnknownCurrency},
+		{"1", "XXX", money.ErrUnknownCurrency},
+		{"1", "", money.ErrUnknownCurrency},
+	} {
+		_, err := money.Parse(c.amount, c.currency)
+		assert.ErrorIs(t, err, c.expected, c.amount+" "+c.currency)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	raw, err := json.Marshal(money.MustParse("197.98", "USD"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"197.98","currency":"USD"}`, string(raw))
+
+	raw, err = json.Marshal(money.Money{})
+	assert.NoError(t, err)
+	assert.Equal(t, "null", string(raw))
+
+	var m money.Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":"0.1","currency":"EUR"}`), &m))
+	assert.Equal(t, money.MustParse("0.10", "EUR"), m)
+
+	assert.NoError(t, json.Unmarshal([]byte(`null`), &m))
+	assert.Equal(t, money.Money{}, m)
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount":"1.001","currency":"EUR"}`), &m), money.ErrInvalidAmount)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"currency":"EUR"}`), &m), money.ErrInvalidAmount)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount":"1"}`), &m), money.ErrUnknownCurrency)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount":1,"currency":"EUR"}`), &m), money.ErrInvalidAmount)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`19.99`), &m), money.ErrInvalidAmount)
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"1","currency":"EUR","rate":1}`), &m))
+}
+
+func TestNew(t *testing.T) {
+	m, err := money.New(19798, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD 197.98", m.String())
+	assert.False(t, m.IsZero())
+	assert.False(t, m.IsNegative())
+
+	_, err = money.New(1, "ABC")
+	assert.ErrorIs(t, err, money.ErrUnknownCurrency)
+}
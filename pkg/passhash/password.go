@@ -0,0 +1,48 @@
+// Package passhash hashes passwords into self-describing strings. A hash names
+// its algorithm and parameters, so it can be verified after the configured
+// hasher changed and detected as weaker than the current policy.
+package passhash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+)
+
+// Hasher hashes new passwords with its algorithm and parameters.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether the hash was made with another algorithm
+	// or with weaker parameters than the hasher uses.
+	NeedsRehash(encoded string) bool
+}
+
+// Verify checks a password against a hash of any supported algorithm.
+func Verify(password, encoded string) (bool, error) {
+	switch {
+	case isBcrypt(encoded):
+		return verifyBcrypt(password, encoded)
+	case strings.HasPrefix(encoded, argon2idPrefix):
+		return verifyArgon2id(password, encoded)
+	default:
+		return false, ErrUnknownAlgorithm
+	}
+}
+
+// NewHasher returns the hasher of the named algorithm, "bcrypt" or "argon2id".
+// Zero parameters take the package defaults.
+func NewHasher(algorithm string, bcryptCost int, argon2 Argon2idParams) (Hasher, error) {
+	switch algorithm {
+	case "", "bcrypt":
+		return NewBcrypt(bcryptCost)
+	case "argon2id":
+		return NewArgon2id(argon2), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, algorithm)
+	}
+}
@@ -0,0 +1,101 @@
+package passhash_test
+
+import (
+	"strings"
+	"testing"
+
+	"app/pkg/passhash"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cheapArgon2id keeps the tests fast.
+var cheapArgon2id = passhash.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestBcrypt(t *testing.T) {
+	hasher, err := passhash.NewBcrypt(5)
+	require.NoError(t, err)
+
+	hash, err := hasher.Hash("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$05$"))
+
+	ok, err := passhash.Verify("secret", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = passhash.Verify("other", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	stronger, err := passhash.NewBcrypt(6)
+	require.NoError(t, err)
+	assert.True(t, stronger.NeedsRehash(hash))
+
+	weaker, err := passhash.NewBcrypt(4)
+	require.NoError(t, err)
+	assert.False(t, weaker.NeedsRehash(hash))
+
+	_, err = passhash.NewBcrypt(40)
+	assert.Error(t, err)
+}
+
+func TestArgon2id(t *testing.T) {
+	hasher := passhash.NewArgon2id(cheapArgon2id)
+
+	hash, err := hasher.Hash("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	ok, err := passhash.Verify("secret", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = passhash.Verify("other", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	stronger := cheapArgon2id
+	stronger.Iterations = 2
+	assert.True(t, passhash.NewArgon2id(stronger).NeedsRehash(hash))
+}
+
+func TestNeedsRehash_OtherAlgorithm(t *testing.T) {
+	bcryptHasher, err := passhash.NewBcrypt(4)
+	require.NoError(t, err)
+	argon2idHasher := passhash.NewArgon2id(cheapArgon2id)
+
+	bcryptHash, err := bcryptHasher.Hash("secret")
+	require.NoError(t, err)
+	argon2idHash, err := argon2idHasher.Hash("secret")
+	require.NoError(t, err)
+
+	assert.True(t, argon2idHasher.NeedsRehash(bcryptHash))
+	assert.True(t, bcryptHasher.NeedsRehash(argon2idHash))
+}
+
+func TestVerify_InvalidHash(t *testing.T) {
+	_, err := passhash.Verify("secret", "plain")
+	assert.ErrorIs(t, err, passhash.ErrUnknownAlgorithm)
+
+	_, err = passhash.Verify("secret", "$argon2id$v=19$m=x$salt$hash")
+	assert.ErrorIs(t, err, passhash.ErrMalformedHash)
+}
+
+func TestNewHasher(t *testing.T) {
+	hasher, err := passhash.NewHasher("argon2id", 0, cheapArgon2id)
+	require.NoError(t, err)
+	assert.IsType(t, &passhash.Argon2id{}, hasher)
+
+	hasher, err = passhash.NewHasher("", 0, passhash.Argon2idParams{})
+	require.NoError(t, err)
+	assert.Equal(t, 10, hasher.(*passhash.Bcrypt).Cost)
+
+	_, err = passhash.NewHasher("md5", 0, passhash.Argon2idParams{})
+	assert.ErrorIs(t, err, passhash.ErrUnknownAlgorithm)
+}
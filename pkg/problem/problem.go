@@ -0,0 +1,72 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// ContentType is the media type of RFC 7807 problem details.
+const ContentType = "application/problem+json"
+
+const (
+	// TypeDefault means the problem has no semantics beyond its status code.
+	TypeDefault = "about:blank"
+	// TypeValidation is a request whose fields failed validation, listed in
+	// Problem.Errors.
+	TypeValidation = "/problems/validation-error"
+)
+
+// FieldError names a field of the request and the rule it broke.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// New returns a problem of the default type about the request r, titled after
+// the status code.
+func New(r *http.Request, status int, detail string) *Problem {
+	return &Problem{
+		Type:      TypeDefault,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+}
+
+// Validation returns a 400 problem listing the fields that failed validation.
+func Validation(r *http.Request, detail string, errs []FieldError) *Problem {
+	p := New(r, http.StatusBadRequest, detail)
+	p.Type = TypeValidation
+	p.Title = "Validation failed"
+	p.Errors = errs
+	return p
+}
+
+// Write sends the problem with its status code. Headers already set on w, such
+// as Retry-After, are kept.
+func (p *Problem) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Write sends a problem of the default type.
+func Write(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	New(r, status, detail).Write(w)
+}
@@ -0,0 +1,58 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"app/pkg/problem"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, http.StatusNotFound, "Product not found")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products/1?x=y", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+
+	var p problem.Problem
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&p))
+	assert.Equal(t, problem.TypeDefault, p.Type)
+	assert.Equal(t, "Not Found", p.Title)
+	assert.Equal(t, http.StatusNotFound, p.Status)
+	assert.Equal(t, "Product not found", p.Detail)
+	assert.Equal(t, "/products/1", p.Instance)
+	assert.NotEmpty(t, p.RequestID)
+	assert.Nil(t, p.Errors)
+}
+
+func TestValidation(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/products", nil)
+
+	errs := []problem.FieldError{
+		{Field: "name", Rule: "required", Message: "name is required"},
+		{Field: "price", Rule: "positive", Message: "price is invalid"},
+	}
+	problem.Validation(r, "name is required; price is invalid", errs).Write(rec)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var body map[string]interface{}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, problem.TypeValidation, body["type"])
+	assert.Equal(t, "Validation failed", body["title"])
+	assert.NotContains(t, body, "request_id")
+
+	fields := body["errors"].([]interface{})
+	assert.Len(t, fields, 2)
+	assert.Equal(t, map[string]interface{}{"field": "price", "rule": "positive", "message": "price is invalid"}, fields[1])
+}